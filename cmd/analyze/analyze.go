@@ -0,0 +1,238 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// output formats
+const (
+	TEXT_OUTPUT = "text"
+	JSON_OUTPUT = "json"
+)
+
+// version of the json output. Bumped when fields change meaning.
+const ANALYSIS_SCHEMA_VERSION = 1
+
+// options constants
+const (
+	SIZE_LONG_OPTION    = "size"
+	SIZE_SHORT_OPTION   = "n"
+	TARGET_LONG_OPTION  = "target"
+	TARGET_SHORT_OPTION = "t"
+	OUTPUT_LONG_OPTION  = "output"
+	OUTPUT_SHORT_OPTION = "o"
+)
+
+// cli args
+type CliArgs struct {
+	target square.MysticSquare
+	output string
+}
+
+// the shape of the state space around a target. Serialized as the json
+// output.
+type Analysis struct {
+	SchemaVersion int    `json:"schema_version"`
+	Size          int    `json:"size"`
+	Target        string `json:"target"`
+	States        uint64 `json:"states"`
+	// largest number of moves any square needs to reach the target
+	Diameter        int     `json:"diameter"`
+	AverageDistance float64 `json:"average_distance"`
+	// average number of legal moves over every state
+	BranchingFactor float64 `json:"branching_factor"`
+	// average number of legal moves other than the one undoing the previous
+	// move, over every state but the target
+	NonReversingBranchingFactor float64 `json:"non_reversing_branching_factor"`
+	// number of states at each distance, starting at 0
+	StatesPerDistance []uint64 `json:"states_per_distance"`
+	// every state at the diameter
+	Antipodes    []string `json:"antipodes"`
+	ElapsedNanos int64    `json:"elapsed_ns"`
+}
+
+// create a new set of Cli Args
+func NewAnalyzeCliArgs() (args *CliArgs, err error) {
+	args = &CliArgs{}
+	switch output := viper.GetString(OUTPUT_LONG_OPTION); output {
+	case TEXT_OUTPUT, JSON_OUTPUT:
+		args.output = output
+	default:
+		args = nil
+		err = fmt.Errorf("unknown output %q, expected %v or %v", output, TEXT_OUTPUT, JSON_OUTPUT)
+		return
+	}
+
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		if args.target, err = square.ParseMysticSquare(targetText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+		}
+		return
+	}
+
+	size := viper.GetInt(SIZE_LONG_OPTION)
+	if size < square.MIN_SIZE {
+		args = nil
+		err = fmt.Errorf("--%v must be at least %v", SIZE_LONG_OPTION, square.MIN_SIZE)
+		return
+	}
+	args.target, err = square.NewMysticSquare(square.SolvedState(size))
+	return
+}
+
+// sweep every state that can reach target and summarize the distances
+func Analyze(target square.MysticSquare) (analysis *Analysis, err error) {
+	startTime := time.Now()
+	distances, err := square.ReachableDistances(target, square.MAX_SWEEP_STATES)
+	if err != nil {
+		return
+	}
+
+	analysis = &Analysis{
+		SchemaVersion:     ANALYSIS_SCHEMA_VERSION,
+		Size:              target.Size(),
+		Target:            square.FormatCompact(target),
+		States:            uint64(len(distances)),
+		StatesPerDistance: make([]uint64, 0),
+		Antipodes:         make([]string, 0),
+	}
+	totalDistance, totalMoves := uint64(0), uint64(0)
+	for rank, distance := range distances {
+		for int(distance) >= len(analysis.StatesPerDistance) {
+			analysis.StatesPerDistance = append(analysis.StatesPerDistance, 0)
+		}
+		analysis.StatesPerDistance[distance]++
+		totalDistance += uint64(distance)
+
+		// the number of legal moves only depends on where the blank is
+		current, unrankErr := square.ReachableUnrank(target, uint64(rank))
+		if unrankErr != nil {
+			analysis = nil
+			err = unrankErr
+			return
+		}
+		totalMoves += uint64(len(square.Neighbors(current)))
+	}
+	analysis.Diameter = len(analysis.StatesPerDistance) - 1
+	analysis.AverageDistance = float64(totalDistance) / float64(analysis.States)
+	analysis.BranchingFactor = float64(totalMoves) / float64(analysis.States)
+	if analysis.States > 1 {
+		targetMoves := uint64(len(square.Neighbors(target)))
+		analysis.NonReversingBranchingFactor = float64(totalMoves-targetMoves-(analysis.States-1)) / float64(analysis.States-1)
+	}
+
+	for rank, distance := range distances {
+		if int(distance) == analysis.Diameter {
+			antipode, _ := square.ReachableUnrank(target, uint64(rank))
+			analysis.Antipodes = append(analysis.Antipodes, square.FormatCompact(antipode))
+		}
+	}
+	analysis.ElapsedNanos = time.Since(startTime).Nanoseconds()
+	return
+}
+
+// write the analysis for people to read
+func (analysis *Analysis) writeText(w io.Writer) (err error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Target:           %v\n", analysis.Target)
+	fmt.Fprintf(&builder, "States:           %v\n", analysis.States)
+	fmt.Fprintf(&builder, "Diameter:         %v\n", analysis.Diameter)
+	fmt.Fprintf(&builder, "Average distance: %.3f\n", analysis.AverageDistance)
+	fmt.Fprintf(&builder, "Branching factor: %.3f (%.3f without undoing the previous move)\n", analysis.BranchingFactor, analysis.NonReversingBranchingFactor)
+	fmt.Fprintf(&builder, "Elapsed:          %v\n", time.Duration(analysis.ElapsedNanos).Round(time.Millisecond))
+
+	fmt.Fprintf(&builder, "\n%8v  %v\n", "distance", "states")
+	for distance, count := range analysis.StatesPerDistance {
+		fmt.Fprintf(&builder, "%8v  %v\n", distance, count)
+	}
+
+	fmt.Fprintf(&builder, "\nAntipodes (%v at distance %v):\n", len(analysis.Antipodes), analysis.Diameter)
+	for _, antipode := range analysis.Antipodes {
+		fmt.Fprintf(&builder, "  %v\n", antipode)
+	}
+	_, err = io.WriteString(w, builder.String())
+	return
+}
+
+// work horse of the entire command
+func executeAnalyze(args *CliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+	analysis, err := Analyze(args.target)
+	if err != nil {
+		return
+	}
+	if args.output == JSON_OUTPUT {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(analysis)
+	} else {
+		err = analysis.writeText(w)
+	}
+	return
+}
+
+// AnalyzeCmd represents the analyze command
+var AnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Sweep every state that can reach a target",
+	Long: `Visit every board that can be solved into the target with a breadth first
+sweep and report how many boards lie at each distance, the diameter (the
+most moves any board needs), every board at the diameter and the average
+branching factor.
+
+The sweep keeps one byte per reachable board, so it covers 2x2 and 3x3
+boards. A 4x4 board has too many arrangements.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewAnalyzeCliArgs(); argsErr == nil {
+			err = executeAnalyze(cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	AnalyzeCmd.Flags().IntP(SIZE_LONG_OPTION, SIZE_SHORT_OPTION, 3, "Width of the board. Ignored when --target is given")
+	AnalyzeCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	AnalyzeCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, TEXT_OUTPUT, fmt.Sprintf("Output format. One of %v, %v", TEXT_OUTPUT, JSON_OUTPUT))
+}
@@ -0,0 +1,430 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/lookup"
+	"mysticsquare/pdb"
+	"mysticsquare/solver"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// output format constants
+const (
+	TEXT_OUTPUT = "text"
+	JSON_OUTPUT = "json"
+)
+
+// version of the json output. Bumped when fields change meaning.
+const BENCHMARK_SCHEMA_VERSION = 1
+
+// options constants
+const (
+	SUITE_LONG_OPTION      = "suite"
+	ALGORITHMS_LONG_OPTION = "algorithms"
+	HEURISTICS_LONG_OPTION = "heuristics"
+	COUNT_LONG_OPTION      = "count"
+	MOVES_LONG_OPTION      = "moves"
+	SEED_LONG_OPTION       = "seed"
+	SIZE_LONG_OPTION       = "size"
+	OPEN_LISTS_LONG_OPTION = "open-lists"
+	PDB_LONG_OPTION        = "pdb"
+	PDB_SHORT_OPTION       = "p"
+	TABLE_LONG_OPTION      = "table"
+	OUTPUT_LONG_OPTION     = "output"
+	OUTPUT_SHORT_OPTION    = "o"
+	TIMEOUT_LONG_OPTION    = "timeout"
+)
+
+// runtime metric sampled for the peak heap size of a search
+const HEAP_METRIC = "/memory/classes/heap/objects:bytes"
+
+// how often the heap is sampled while a search runs
+const HEAP_SAMPLE_INTERVAL = time.Millisecond
+
+// a single puzzle of the suite
+type benchmarkPuzzle struct {
+	initial square.MysticSquare
+	target  square.MysticSquare
+}
+
+// benchmark cli args
+type BenchmarkCliArgs struct {
+	suite      []benchmarkPuzzle
+	algorithms []solver.AlgorithmSelection
+	heuristics []solver.HeuristicSelection
+	openLists  []string
+	db         *pdb.PatternDatabase
+	pdbFile    string
+	table      *lookup.Table
+	tableFile  string
+	output     string
+	timeout    time.Duration
+}
+
+// totals for one algorithm and heuristic pair over the whole suite
+type BenchmarkRow struct {
+	Algorithm     string        `json:"algorithm"`
+	Heuristic     string        `json:"heuristic"`
+	OpenList      string        `json:"open_list"`
+	Puzzles       int           `json:"puzzles"`
+	Solved        int           `json:"solved"`
+	TotalTime     time.Duration `json:"total_time_ns"`
+	NodesExpanded int           `json:"nodes_expanded"`
+	PeakHeapBytes uint64        `json:"peak_heap_bytes"`
+	TotalMoves    int           `json:"total_moves"`
+	AverageTime   time.Duration `json:"average_time_ns"`
+	AverageNodes  float64       `json:"average_nodes_expanded"`
+	AverageLength float64       `json:"average_length"`
+	Failures      []string      `json:"failures"`
+}
+
+// parse a comma separated list of numeric selections
+func parseSelections(text string) (selections []int, err error) {
+	selections = make([]int, 0)
+	for _, token := range strings.Split(text, ",") {
+		if token = strings.TrimSpace(token); token == "" {
+			continue
+		}
+		selection, convErr := strconv.Atoi(token)
+		if convErr != nil {
+			selections = nil
+			err = fmt.Errorf("not a number: %q", token)
+			return
+		}
+		selections = append(selections, selection)
+	}
+	return
+}
+
+// read the suite from a file, one puzzle per line in the batch format
+func readSuite(path string) (suite []benchmarkPuzzle, err error) {
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		err = readErr
+		return
+	}
+	suite = make([]benchmarkPuzzle, 0)
+	for idx, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		initial, target, parseErr := square.ParsePuzzle(line)
+		if parseErr != nil {
+			suite = nil
+			err = fmt.Errorf("%v:%v: %v", path, idx+1, parseErr)
+			return
+		}
+		suite = append(suite, benchmarkPuzzle{initial: initial, target: target})
+	}
+	return
+}
+
+// create a new set of benchmark Cli Args
+func NewBenchmarkCliArgs() (args *BenchmarkCliArgs, err error) {
+	args = &BenchmarkCliArgs{output: viper.GetString(OUTPUT_LONG_OPTION), pdbFile: viper.GetString(PDB_LONG_OPTION), tableFile: viper.GetString(TABLE_LONG_OPTION), timeout: viper.GetDuration(TIMEOUT_LONG_OPTION)}
+	if args.output != TEXT_OUTPUT && args.output != JSON_OUTPUT {
+		args = nil
+		err = fmt.Errorf("--%v must be %v or %v", OUTPUT_LONG_OPTION, TEXT_OUTPUT, JSON_OUTPUT)
+		return
+	}
+
+	algorithms, algorithmsErr := parseSelections(viper.GetString(ALGORITHMS_LONG_OPTION))
+	if algorithmsErr != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", ALGORITHMS_LONG_OPTION, algorithmsErr)
+		return
+	}
+	for _, selection := range algorithms {
+		algorithm := solver.AlgorithmSelection(selection)
+		if algorithm.String() == "unknown" {
+			args = nil
+			err = fmt.Errorf("--%v: unknown algorithm %v", ALGORITHMS_LONG_OPTION, selection)
+			return
+		}
+		if algorithm == solver.LOOKUP_TABLE {
+			if args.tableFile == "" {
+				args = nil
+				err = fmt.Errorf("the lookup table algorithm needs --%v", TABLE_LONG_OPTION)
+				return
+			}
+			if args.table, err = lookup.LoadFile(args.tableFile); err != nil {
+				args = nil
+				return
+			}
+		}
+		args.algorithms = append(args.algorithms, algorithm)
+	}
+
+	heuristics, heuristicsErr := parseSelections(viper.GetString(HEURISTICS_LONG_OPTION))
+	if heuristicsErr != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", HEURISTICS_LONG_OPTION, heuristicsErr)
+		return
+	}
+	for _, selection := range heuristics {
+		heuristic := solver.HeuristicSelection(selection)
+		if heuristic.String() == "unknown" {
+			args = nil
+			err = fmt.Errorf("--%v: unknown heuristic %v", HEURISTICS_LONG_OPTION, selection)
+			return
+		}
+		if heuristic == solver.PATTERN_DATABASE {
+			if args.pdbFile == "" {
+				args = nil
+				err = fmt.Errorf("the pattern database heuristic needs --%v", PDB_LONG_OPTION)
+				return
+			}
+			if args.db, err = pdb.LoadFile(args.pdbFile); err != nil {
+				args = nil
+				return
+			}
+		}
+		args.heuristics = append(args.heuristics, heuristic)
+	}
+
+	for _, openList := range strings.Split(viper.GetString(OPEN_LISTS_LONG_OPTION), ",") {
+		switch openList = strings.TrimSpace(openList); openList {
+		case "":
+		case solver.HEAP_OPEN_LIST, solver.BUCKET_OPEN_LIST:
+			args.openLists = append(args.openLists, openList)
+		default:
+			args = nil
+			err = fmt.Errorf("--%v: unknown open list %q", OPEN_LISTS_LONG_OPTION, openList)
+			return
+		}
+	}
+
+	if suitePath := viper.GetString(SUITE_LONG_OPTION); suitePath != "" {
+		if args.suite, err = readSuite(suitePath); err != nil {
+			args = nil
+		}
+		return
+	}
+
+	size, count, moves := viper.GetInt(SIZE_LONG_OPTION), viper.GetInt(COUNT_LONG_OPTION), viper.GetInt(MOVES_LONG_OPTION)
+	if size < square.MIN_SIZE || count < 1 || moves < 0 {
+		args = nil
+		err = fmt.Errorf("--%v, --%v and --%v must describe at least one board", SIZE_LONG_OPTION, COUNT_LONG_OPTION, MOVES_LONG_OPTION)
+		return
+	}
+	target, targetErr := square.NewMysticSquare(square.SolvedState(size))
+	if targetErr != nil {
+		args = nil
+		err = targetErr
+		return
+	}
+	seed := viper.GetUint64(SEED_LONG_OPTION)
+	rng := rand.New(rand.NewPCG(seed, seed))
+	for idx := 0; idx < count; idx++ {
+		args.suite = append(args.suite, benchmarkPuzzle{initial: square.RandomWalk(rng, target, moves), target: target})
+	}
+	return
+}
+
+// highest heap size seen while run executes, above the heap size before it
+func measurePeakHeap(run func()) (peak uint64) {
+	sample := []metrics.Sample{{Name: HEAP_METRIC}}
+	read := func() (bytes uint64) {
+		metrics.Read(sample)
+		if sample[0].Value.Kind() == metrics.KindUint64 {
+			bytes = sample[0].Value.Uint64()
+		}
+		return
+	}
+
+	runtime.GC()
+	baseline := read()
+	highest := baseline
+	done := make(chan struct{})
+	sampled := make(chan uint64)
+	go func() {
+		ticker := time.NewTicker(HEAP_SAMPLE_INTERVAL)
+		defer ticker.Stop()
+		observed := baseline
+		for {
+			select {
+			case <-done:
+				sampled <- max(observed, read())
+				return
+			case <-ticker.C:
+				observed = max(observed, read())
+			}
+		}
+	}()
+	run()
+	close(done)
+	highest = max(highest, <-sampled)
+	peak = highest - baseline
+	return
+}
+
+// run the suite through one algorithm, heuristic and open list
+func (args *BenchmarkCliArgs) benchmarkPair(ctx context.Context, algorithm solver.AlgorithmSelection, heuristic solver.HeuristicSelection, openList string) (row BenchmarkRow) {
+	options := solver.Options{Algorithm: algorithm, Heuristic: heuristic, OpenList: openList, PatternDatabase: args.db, Table: args.table, Timeout: args.timeout}
+	row = BenchmarkRow{Algorithm: algorithm.String(), Heuristic: "none", OpenList: "none", Failures: make([]string, 0)}
+	if algorithm.Informed() {
+		row.Heuristic = heuristic.String()
+	}
+	if algorithm.UsesOpenList() {
+		row.OpenList = openList
+	}
+
+	for _, puzzle := range args.suite {
+		var result *solver.Result
+		var err error
+		peak := measurePeakHeap(func() {
+			result, err = solver.Solve(ctx, puzzle.initial, puzzle.target, options)
+		})
+		row.Puzzles++
+		row.PeakHeapBytes = max(row.PeakHeapBytes, peak)
+		switch {
+		case err != nil:
+			row.Failures = append(row.Failures, fmt.Sprintf("%v: %v", square.FormatCompact(puzzle.initial), err))
+			continue
+		case result.Outcome != solver.SOLVED_OUTCOME:
+			row.Failures = append(row.Failures, fmt.Sprintf("%v: %v", square.FormatCompact(puzzle.initial), result.Outcome))
+		default:
+			row.Solved++
+			row.TotalMoves += result.Cost
+		}
+		row.TotalTime += result.Statistics.WallTime
+		row.NodesExpanded += result.Statistics.NodesExpanded
+	}
+
+	if row.Puzzles > 0 {
+		row.AverageTime = row.TotalTime / time.Duration(row.Puzzles)
+		row.AverageNodes = float64(row.NodesExpanded) / float64(row.Puzzles)
+	}
+	if row.Solved > 0 {
+		row.AverageLength = float64(row.TotalMoves) / float64(row.Solved)
+	}
+	return
+}
+
+// write the comparison table
+func writeBenchmarkTable(w io.Writer, rows []BenchmarkRow) (err error) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "algorithm\theuristic\topen list\tsolved\ttotal time\tavg time\tavg nodes expanded\tpeak heap\tavg length")
+	for _, row := range rows {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v/%v\t%v\t%v\t%.1f\t%.1f KiB\t%.2f\n",
+			row.Algorithm, row.Heuristic, row.OpenList, row.Solved, row.Puzzles, row.TotalTime.Round(time.Microsecond), row.AverageTime.Round(time.Microsecond),
+			row.AverageNodes, float64(row.PeakHeapBytes)/1024, row.AverageLength)
+	}
+	err = table.Flush()
+	return
+}
+
+// work horse of the benchmark command
+func executeBenchmark(ctx context.Context, args *BenchmarkCliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+	if len(args.suite) == 0 {
+		err = fmt.Errorf("empty suite")
+		return
+	}
+
+	rows := make([]BenchmarkRow, 0)
+	for _, algorithm := range args.algorithms {
+		heuristics := args.heuristics
+		if !algorithm.Informed() {
+			heuristics = []solver.HeuristicSelection{solver.MANHATTAN_DISTANCE}
+		}
+		openLists := args.openLists
+		if !algorithm.UsesOpenList() {
+			openLists = []string{solver.HEAP_OPEN_LIST}
+		}
+		for _, heuristic := range heuristics {
+			for _, openList := range openLists {
+				rows = append(rows, args.benchmarkPair(ctx, algorithm, heuristic, openList))
+			}
+		}
+	}
+
+	if args.output == JSON_OUTPUT {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(map[string]any{"schema_version": BENCHMARK_SCHEMA_VERSION, "puzzles": len(args.suite), "rows": rows})
+		return
+	}
+	fmt.Fprintf(w, "%v puzzles\n", len(args.suite))
+	err = writeBenchmarkTable(w, rows)
+	return
+}
+
+// BenchmarkCmd represents the benchmark command
+var BenchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Compare algorithms and heuristics on a suite of puzzles",
+	Long: `Run every puzzle of a suite through each selected algorithm and heuristic
+and print a comparison table of time, nodes expanded, peak heap and solution
+length. Uninformed algorithms are run once regardless of the heuristics. A star
+and Dijkstras are run once with each selected open list.
+
+The suite is read from --suite in the batch format of run, or generated by
+scrambling the solved board with --moves random moves.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewBenchmarkCliArgs(); argsErr == nil {
+			err = executeBenchmark(cmd.Context(), cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	BenchmarkCmd.Flags().String(SUITE_LONG_OPTION, "", "File of puzzles, one per line in the batch format of run")
+	BenchmarkCmd.Flags().String(ALGORITHMS_LONG_OPTION, fmt.Sprintf("%v,%v,%v,%v,%v", int(solver.A_STAR_SEARCH), int(solver.DIJKSTRAS_ALGORITHM), int(solver.BREADTH_FIRST_SEARCH), int(solver.IDA_STAR_SEARCH), int(solver.BIDIRECTIONAL_BFS)), "Comma separated algorithms to compare. "+config.AlgorithmDescription(TABLE_LONG_OPTION))
+	BenchmarkCmd.Flags().String(HEURISTICS_LONG_OPTION, fmt.Sprintf("%v,%v", int(solver.MANHATTAN_DISTANCE), int(solver.LINEAR_CONFLICT)), "Comma separated heuristics to compare for informed searches. "+config.HeuristicDescription(PDB_LONG_OPTION))
+	BenchmarkCmd.Flags().String(OPEN_LISTS_LONG_OPTION, solver.HEAP_OPEN_LIST+","+solver.BUCKET_OPEN_LIST, "Comma separated open lists to compare for A star and Dijkstras. Any of "+solver.HEAP_OPEN_LIST+", "+solver.BUCKET_OPEN_LIST)
+	BenchmarkCmd.Flags().StringP(PDB_LONG_OPTION, PDB_SHORT_OPTION, "", "Pattern database file built with the pdb command")
+	BenchmarkCmd.Flags().String(TABLE_LONG_OPTION, "", "Lookup table file built with the table build command")
+	BenchmarkCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, TEXT_OUTPUT, fmt.Sprintf("Output format. One of %v, %v", TEXT_OUTPUT, JSON_OUTPUT))
+	BenchmarkCmd.Flags().Duration(TIMEOUT_LONG_OPTION, 0, "Stop a search that runs longer than this. 0 means no limit")
+	BenchmarkCmd.Flags().Int(SIZE_LONG_OPTION, 3, "Width of generated boards")
+	BenchmarkCmd.Flags().Int(COUNT_LONG_OPTION, 5, "Number of generated boards")
+	BenchmarkCmd.Flags().Int(MOVES_LONG_OPTION, 20, "Random moves used to scramble each generated board")
+	BenchmarkCmd.Flags().Uint64(SEED_LONG_OPTION, 1, "Seed for generated boards")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// byte size suffixes accepted by ParseByteSize
+var byteSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+}
+
+// parse a size such as 512MiB, 2GB or 1048576 into bytes. Empty means 0.
+func ParseByteSize(text string) (bytes int64, err error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" {
+		return
+	}
+	multiplier := int64(1)
+	for _, candidate := range byteSuffixes {
+		if strings.HasSuffix(text, candidate.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, candidate.suffix))
+			multiplier = candidate.multiplier
+			break
+		}
+	}
+	value, parseErr := strconv.ParseFloat(text, 64)
+	if parseErr != nil || math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		err = fmt.Errorf("invalid size %q", text)
+		return
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which no int64 holds
+	scaled := value * float64(multiplier)
+	if scaled >= float64(math.MaxInt64) {
+		err = fmt.Errorf("size %q is too large", text)
+		return
+	}
+	bytes = int64(scaled)
+	return
+}
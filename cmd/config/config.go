@@ -0,0 +1,52 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"fmt"
+
+	"mysticsquare/solver"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bind the flags of the command being run. Done before each run rather than
+// on initialization so commands sharing a flag name do not override each other.
+func BindFlags(cmd *cobra.Command) {
+	viper.BindPFlags(cmd.InheritedFlags())
+	viper.BindPFlags(cmd.LocalFlags())
+}
+
+// description of an algorithm flag. tableOption is the flag that names the
+// lookup table file.
+func AlgorithmDescription(tableOption string) (description string) {
+	description = fmt.Sprintf("Algorithm to use. A star: %v, Dijkstras: %v, BFS: %v, IDA star: %v, Bidirectional BFS: %v, Lookup table (needs --%v): %v", int(solver.A_STAR_SEARCH), int(solver.DIJKSTRAS_ALGORITHM), int(solver.BREADTH_FIRST_SEARCH), int(solver.IDA_STAR_SEARCH), int(solver.BIDIRECTIONAL_BFS), tableOption, int(solver.LOOKUP_TABLE))
+	return
+}
+
+// description of a heuristic flag. pdbOption is the flag that names the
+// pattern database file.
+func HeuristicDescription(pdbOption string) (description string) {
+	description = fmt.Sprintf("Heuristic used by informed searches. Manhattan distance: %v, Linear conflict: %v, Pattern database (needs --%v): %v", int(solver.MANHATTAN_DISTANCE), int(solver.LINEAR_CONFLICT), pdbOption, int(solver.PATTERN_DATABASE))
+	return
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package generate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// options constants
+const (
+	MODE_LONG_OPTION    = "mode"
+	MODE_SHORT_OPTION   = "m"
+	SEED_LONG_OPTION    = "seed"
+	SIZE_LONG_OPTION    = "size"
+	SIZE_SHORT_OPTION   = "n"
+	TARGET_LONG_OPTION  = "target"
+	TARGET_SHORT_OPTION = "t"
+	MOVES_LONG_OPTION   = "moves"
+	COUNT_LONG_OPTION   = "count"
+	COUNT_SHORT_OPTION  = "c"
+)
+
+// cli args
+type CliArgs struct {
+	mode   string
+	seed   uint64
+	target square.MysticSquare
+	moves  int
+	count  int
+}
+
+// create a new set of Cli Args
+func NewGenerateCliArgs() (args *CliArgs, err error) {
+	args = &CliArgs{mode: viper.GetString(MODE_LONG_OPTION), moves: viper.GetInt(MOVES_LONG_OPTION), count: viper.GetInt(COUNT_LONG_OPTION)}
+	if args.seed = viper.GetUint64(SEED_LONG_OPTION); args.seed == 0 {
+		args.seed = uint64(time.Now().UnixNano())
+	}
+
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		if args.target, err = square.ParseMysticSquare(targetText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+		}
+		return
+	}
+
+	size := viper.GetInt(SIZE_LONG_OPTION)
+	if err = (square.GenerateLimits{}).CheckSize(size); err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", SIZE_LONG_OPTION, err)
+		return
+	}
+	args.target, err = square.NewMysticSquare(square.SolvedState(size))
+	return
+}
+
+// work horse of the entire command. Boards are written one per line in the
+// compact form read by run --start.
+func executeGenerate(ctx context.Context, args *CliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	rng := rand.New(rand.NewPCG(args.seed, args.seed))
+	boards, err := square.Generate(ctx, rng, args.target, args.mode, args.moves, args.count, square.GenerateLimits{})
+	if err != nil {
+		return
+	}
+
+	for _, board := range boards {
+		if _, err = fmt.Fprintln(w, square.FormatCompact(board)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// GenerateCmd represents the generate command
+var GenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate random solvable mystic squares",
+	Long: `Generate random boards that can be solved into the target, one per line in
+the form read by run --start.
+
+Modes:
+  uniform   every solvable board is equally likely
+  walk      scramble the target with --moves random moves
+  distance  every board whose optimal solution is exactly --moves moves is
+            equally likely. Searches every board within --moves of the target.
+
+The same --seed always produces the same boards.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewGenerateCliArgs(); argsErr == nil {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			err = executeGenerate(ctx, cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	GenerateCmd.Flags().StringP(MODE_LONG_OPTION, MODE_SHORT_OPTION, square.UNIFORM_MODE, fmt.Sprintf("How boards are chosen. One of %v, %v, %v", square.UNIFORM_MODE, square.WALK_MODE, square.DISTANCE_MODE))
+	GenerateCmd.Flags().Uint64(SEED_LONG_OPTION, 0, "Seed for the random generator. 0 picks one from the clock")
+	GenerateCmd.Flags().IntP(SIZE_LONG_OPTION, SIZE_SHORT_OPTION, 3, "Width of the board. Ignored when --target is given")
+	GenerateCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	GenerateCmd.Flags().Int(MOVES_LONG_OPTION, 20, fmt.Sprintf("Scramble length for %v, exact optimal distance for %v", square.WALK_MODE, square.DISTANCE_MODE))
+	GenerateCmd.Flags().IntP(COUNT_LONG_OPTION, COUNT_SHORT_OPTION, 1, "Number of boards to generate")
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pdb
+
+import (
+	"fmt"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/pdb"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// options constants
+const (
+	SIZE_LONG_OPTION       = "size"
+	SIZE_SHORT_OPTION      = "n"
+	PARTITION_LONG_OPTION  = "partition"
+	PARTITION_SHORT_OPTION = "p"
+	TARGET_LONG_OPTION     = "target"
+	TARGET_SHORT_OPTION    = "t"
+	OUTPUT_LONG_OPTION     = "output"
+	OUTPUT_SHORT_OPTION    = "o"
+)
+
+// tiles per pattern when no partition is given
+const DEFAULT_GROUP_SIZE = 5
+
+// cli args
+type CliArgs struct {
+	size      int
+	partition [][]int
+	target    square.MysticSquare
+	output    string
+}
+
+// create a new set of Cli Args
+func NewPdbCliArgs() (args *CliArgs, err error) {
+	args = &CliArgs{}
+	args.output = viper.GetString(OUTPUT_LONG_OPTION)
+	if args.output == "" {
+		args = nil
+		err = fmt.Errorf("--%v is required", OUTPUT_LONG_OPTION)
+		return
+	}
+
+	args.size = viper.GetInt(SIZE_LONG_OPTION)
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		if args.target, err = square.ParseMysticSquare(targetText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+			return
+		}
+		args.size = args.target.Size()
+	} else {
+		if args.size < square.MIN_SIZE {
+			args = nil
+			err = fmt.Errorf("--%v must be at least %v", SIZE_LONG_OPTION, square.MIN_SIZE)
+			return
+		}
+		args.target, err = square.NewMysticSquare(square.SolvedState(args.size))
+	}
+
+	if partitionText := viper.GetString(PARTITION_LONG_OPTION); partitionText != "" {
+		if args.partition, err = pdb.ParsePartition(partitionText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", PARTITION_LONG_OPTION, err)
+			return
+		}
+	} else {
+		args.partition = pdb.DefaultPartition(args.size, DEFAULT_GROUP_SIZE)
+	}
+	return
+}
+
+// work horse of the entire command
+func executePdb(args *CliArgs) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	db, buildErr := pdb.NewPatternDatabase(args.target, args.partition)
+	if buildErr != nil {
+		err = buildErr
+		return
+	}
+	if err = db.SaveFile(args.output); err != nil {
+		return
+	}
+	for _, pattern := range db.Patterns {
+		fmt.Printf("pattern %v: %v entries\n", pattern.Tiles, len(pattern.Distances))
+	}
+	fmt.Printf("saved %v\n", args.output)
+	return
+}
+
+// PdbCmd represents the pdb command
+var PdbCmd = &cobra.Command{
+	Use:   "pdb",
+	Short: "Build an additive pattern database",
+	Long: `Build an additive disjoint pattern database for a target board and save it
+to a file that run can load with --pdb.
+
+Tiles are split into patterns with --partition, e.g. 1,2,3,4,5/6,7,8,9,10/11,12,13,14,15.
+Every pattern of k tiles on an NxN board takes N*N!/(N*N-k)! bytes.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewPdbCliArgs(); argsErr == nil {
+			err = executePdb(cliArgs)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	PdbCmd.Flags().IntP(SIZE_LONG_OPTION, SIZE_SHORT_OPTION, 4, "Width of the board. Ignored when --target is given")
+	PdbCmd.Flags().StringP(PARTITION_LONG_OPTION, PARTITION_SHORT_OPTION, "", fmt.Sprintf("Disjoint groups of tiles, one pattern per group. Defaults to groups of %v tiles in order", DEFAULT_GROUP_SIZE))
+	PdbCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	PdbCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, "", "File to save the pattern database to")
+}
@@ -0,0 +1,461 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package play
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/solver"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// options constants
+const (
+	START_LONG_OPTION        = "start"
+	START_SHORT_OPTION       = "s"
+	TARGET_LONG_OPTION       = "target"
+	TARGET_SHORT_OPTION      = "t"
+	CONVENTION_LONG_OPTION   = "convention"
+	CONVENTION_SHORT_OPTION  = "c"
+	HINT_TIMEOUT_LONG_OPTION = "hint-timeout"
+	SIZE_LONG_OPTION         = "size"
+	MOVES_LONG_OPTION        = "moves"
+	SEED_LONG_OPTION         = "seed"
+)
+
+// actions a key press can trigger besides a move
+const (
+	UNDO_ACTION = "undo"
+	HINT_ACTION = "hint"
+	QUIT_ACTION = "quit"
+)
+
+// how often the clock is redrawn while waiting for a key
+const PLAY_REDRAW_INTERVAL = time.Second
+
+// how long to wait for the rest of an escape sequence before taking ESC as a
+// key on its own. Terminals send the whole sequence at once.
+const ESCAPE_SEQUENCE_TIMEOUT = 50 * time.Millisecond
+
+// escape sequence that clears the terminal and moves the cursor home
+const CLEAR_SCREEN = "\x1b[H\x1b[2J"
+
+// action for each plain key
+var playKeys = map[byte]string{
+	'w': square.UP, 'W': square.UP,
+	's': square.DOWN, 'S': square.DOWN,
+	'a': square.LEFT, 'A': square.LEFT,
+	'd': square.RIGHT, 'D': square.RIGHT,
+	'u': UNDO_ACTION, 'U': UNDO_ACTION, 0x7f: UNDO_ACTION, '\b': UNDO_ACTION,
+	'h': HINT_ACTION, 'H': HINT_ACTION, '?': HINT_ACTION,
+	'q': QUIT_ACTION, 'Q': QUIT_ACTION, 0x03: QUIT_ACTION, 0x04: QUIT_ACTION,
+}
+
+// direction for the final byte of an arrow key escape sequence
+var arrowKeys = map[byte]string{'A': square.UP, 'B': square.DOWN, 'C': square.RIGHT, 'D': square.LEFT}
+
+// play cli args
+type PlayCliArgs struct {
+	start       square.MysticSquare
+	target      square.MysticSquare
+	convention  square.MoveConvention
+	hintTimeout time.Duration
+}
+
+// create a new set of play Cli Args. Without --start the board is a random
+// solvable board, or the target scrambled with --moves random moves.
+func NewPlayCliArgs() (args *PlayCliArgs, err error) {
+	args = &PlayCliArgs{hintTimeout: viper.GetDuration(HINT_TIMEOUT_LONG_OPTION)}
+	if args.convention, err = square.ParseMoveConvention(viper.GetString(CONVENTION_LONG_OPTION)); err != nil {
+		args = nil
+		return
+	}
+
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		if args.target, err = square.ParseMysticSquare(targetText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+			return
+		}
+	}
+
+	if startText := viper.GetString(START_LONG_OPTION); startText != "" {
+		if args.start, err = square.ParseMysticSquare(startText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", START_LONG_OPTION, err)
+			return
+		}
+	}
+
+	if args.target == nil {
+		size := viper.GetInt(SIZE_LONG_OPTION)
+		if args.start != nil {
+			size = args.start.Size()
+		}
+		if size < square.MIN_SIZE {
+			args = nil
+			err = fmt.Errorf("--%v must be at least %v", SIZE_LONG_OPTION, square.MIN_SIZE)
+			return
+		}
+		if args.target, err = square.NewMysticSquare(square.SolvedState(size)); err != nil {
+			args = nil
+			return
+		}
+	}
+
+	if args.start == nil {
+		seed := viper.GetUint64(SEED_LONG_OPTION)
+		if seed == 0 {
+			seed = uint64(time.Now().UnixNano())
+		}
+		rng := rand.New(rand.NewPCG(seed, seed))
+		if moves := viper.GetInt(MOVES_LONG_OPTION); moves > 0 {
+			args.start = square.RandomWalk(rng, args.target, moves)
+		} else if args.start, err = square.RandomSolvable(rng, args.target); err != nil {
+			args = nil
+			return
+		}
+	}
+
+	if solvable, reason := square.Solvable(args.start, args.target); !solvable {
+		args = nil
+		err = fmt.Errorf("the board can not be solved: %v", reason)
+	}
+	return
+}
+
+// state of a single game
+type playGame struct {
+	current square.MysticSquare
+	target  square.MysticSquare
+	history []square.MysticSquare
+	started time.Time
+	message string
+}
+
+// start a game on the given board
+func newPlayGame(start, target square.MysticSquare) (game *playGame) {
+	game = &playGame{current: start, target: target, history: make([]square.MysticSquare, 0), started: time.Now()}
+	return
+}
+
+// number of moves made and not undone
+func (game *playGame) moves() (count int) {
+	count = len(game.history)
+	return
+}
+
+// check if the target was reached
+func (game *playGame) solved() (solved bool) {
+	solved = square.KeyOf(game.current) == square.KeyOf(game.target)
+	return
+}
+
+// move the empty space in the given direction
+func (game *playGame) move(direction string) (moved bool) {
+	next := square.Move(game.current, direction)
+	if moved = next != nil; moved {
+		game.history = append(game.history, game.current)
+		game.current = next
+	}
+	return
+}
+
+// take back the last move
+func (game *playGame) undo() (undone bool) {
+	if undone = len(game.history) > 0; undone {
+		game.current = game.history[len(game.history)-1]
+		game.history = game.history[:len(game.history)-1]
+	}
+	return
+}
+
+// first move of an optimal solution, or why there is none
+type playHint struct {
+	direction string
+	remaining int
+	err       error
+}
+
+// ask the solver for the first move of an optimal solution from current.
+// remaining is the length of that solution.
+func findHint(ctx context.Context, current, target square.MysticSquare, timeout time.Duration) (hint playHint) {
+	options := solver.Options{Algorithm: solver.IDA_STAR_SEARCH, Heuristic: solver.LINEAR_CONFLICT, Timeout: timeout}
+	result, err := solver.Solve(ctx, current, target, options)
+	switch {
+	case err != nil:
+		hint.err = err
+	case result.Outcome != solver.SOLVED_OUTCOME:
+		hint.err = fmt.Errorf("no hint: %v", result.Outcome)
+	case result.Cost == 0:
+		hint.err = fmt.Errorf("no hint: already solved")
+	default:
+		hint.direction = result.Moves[0]
+		hint.remaining = result.Cost
+	}
+	return
+}
+
+// look for a hint from the current board in the background. The hint arrives
+// on hints; cancel stops the search.
+func (game *playGame) startHint(ctx context.Context, timeout time.Duration) (hints <-chan playHint, cancel context.CancelFunc) {
+	ctx, cancel = context.WithCancel(ctx)
+	found := make(chan playHint, 1)
+	go func(current, target square.MysticSquare) {
+		found <- findHint(ctx, current, target, timeout)
+	}(game.current, game.target)
+	hints = found
+	return
+}
+
+// write the board, the target and the counters
+func (game *playGame) render(w io.Writer, convention square.MoveConvention, interactive bool) (err error) {
+	var builder strings.Builder
+	if interactive {
+		builder.WriteString(CLEAR_SCREEN)
+	}
+	fmt.Fprintf(&builder, "%v\n\nTarget\n%v\n\n", game.current.State(), game.target.State())
+	fmt.Fprintf(&builder, "Moves: %v   Time: %v\n", game.moves(), time.Since(game.started).Round(time.Second))
+	if game.message != "" {
+		fmt.Fprintln(&builder, game.message)
+	}
+	if convention == square.TILE_MOVES {
+		fmt.Fprintln(&builder, "arrows or WASD slide a tile into the blank, u undo, h hint, q quit")
+	} else {
+		fmt.Fprintln(&builder, "arrows or WASD move the blank, u undo, h hint, q quit")
+	}
+	text := builder.String()
+	if interactive {
+		// the terminal is in raw mode and does not return the carriage
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	_, err = io.WriteString(w, text)
+	return
+}
+
+// next byte of keys, unless none arrives within ESCAPE_SEQUENCE_TIMEOUT
+func nextSequenceByte(keys <-chan byte) (key byte, ok bool) {
+	timer := time.NewTimer(ESCAPE_SEQUENCE_TIMEOUT)
+	defer timer.Stop()
+	select {
+	case key, ok = <-keys:
+	case <-timer.C:
+	}
+	return
+}
+
+// read the next key press and return the action it stands for. Keys that do
+// nothing give an empty action. ok is false once keys is closed.
+func readPlayAction(keys <-chan byte) (action string, ok bool) {
+	key, ok := <-keys
+	if !ok || key != 0x1b {
+		action = playKeys[key]
+		return
+	}
+	// arrow keys arrive as ESC [ A..D. A lone ESC does nothing.
+	if next, more := nextSequenceByte(keys); more && next == '[' {
+		if final, more := nextSequenceByte(keys); more {
+			action = arrowKeys[final]
+		}
+	}
+	return
+}
+
+// work horse of the play command. Keys are read from in, which is switched to
+// raw mode when it is a terminal.
+func executePlay(ctx context.Context, args *PlayCliArgs, in *os.File, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	fd := int(in.Fd())
+	interactive := term.IsTerminal(fd)
+	var redraw <-chan time.Time
+	if interactive {
+		oldState, rawErr := term.MakeRaw(fd)
+		if rawErr != nil {
+			err = rawErr
+			return
+		}
+		defer term.Restore(fd, oldState)
+		ticker := time.NewTicker(PLAY_REDRAW_INTERVAL)
+		defer ticker.Stop()
+		redraw = ticker.C
+	}
+
+	keys := make(chan byte)
+	var readErr error
+	go func() {
+		defer close(keys)
+		reader := bufio.NewReader(in)
+		for {
+			key, keyErr := reader.ReadByte()
+			if keyErr != nil {
+				readErr = keyErr
+				return
+			}
+			select {
+			case keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// the read error is passed on after every action before it
+	actions := make(chan string)
+	readErrs := make(chan error, 1)
+	go func() {
+		for {
+			action, ok := readPlayAction(keys)
+			if !ok {
+				readErrs <- readErr
+				return
+			}
+			select {
+			case actions <- action:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// a hint is looked for while keys are still read. Moving or undoing
+	// abandons it, as it no longer fits the board.
+	var hints <-chan playHint
+	cancelHint := func() {}
+	defer func() { cancelHint() }()
+	abandonHint := func() {
+		cancelHint()
+		hints, cancelHint = nil, func() {}
+	}
+
+	game := newPlayGame(args.start, args.target)
+	newline := "\n"
+	if interactive {
+		newline = "\r\n"
+	}
+	for !game.solved() {
+		if err = game.render(w, args.convention, interactive); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-redraw:
+		case readErr := <-readErrs:
+			if readErr != nil && !errors.Is(readErr, io.EOF) {
+				err = readErr
+			}
+			return
+		case hint := <-hints:
+			abandonHint()
+			if hint.err != nil {
+				game.message = hint.err.Error()
+			} else {
+				game.message = fmt.Sprintf("Hint: %v, %v moves from the target", square.FormatMoves([]string{hint.direction}, args.convention), hint.remaining)
+			}
+		case action := <-actions:
+			game.message = ""
+			switch action {
+			case "":
+			case QUIT_ACTION:
+				fmt.Fprintf(w, "Quit after %v moves%v", game.moves(), newline)
+				return
+			case UNDO_ACTION:
+				if game.undo() {
+					abandonHint()
+				} else {
+					game.message = "Nothing to undo"
+				}
+			case HINT_ACTION:
+				if hints == nil {
+					hints, cancelHint = game.startHint(ctx, args.hintTimeout)
+				}
+			default:
+				if game.move(args.convention.BlankDirection(action)) {
+					abandonHint()
+				} else {
+					game.message = "That move leaves the board"
+				}
+			}
+			if hints != nil && game.message == "" {
+				game.message = "Looking for a hint..."
+			}
+		}
+	}
+
+	if err = game.render(w, args.convention, interactive); err == nil {
+		_, err = fmt.Fprintf(w, "Solved in %v moves and %v%v", game.moves(), time.Since(game.started).Round(time.Millisecond), newline)
+	}
+	return
+}
+
+// PlayCmd represents the play command
+var PlayCmd = &cobra.Command{
+	Use:   "play",
+	Short: "Play a mystic square in the terminal",
+	Long: `Play a mystic square in the terminal. Arrow keys or WASD move the blank, or
+slide a tile into it with --convention tile. u or backspace undoes the last
+move, h asks the solver for the next move of an optimal solution and q quits.
+
+Without --start the board is a random solvable board, or the target scrambled
+with --moves random moves.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewPlayCliArgs(); argsErr == nil {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			err = executePlay(ctx, cliArgs, os.Stdin, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	PlayCmd.Flags().StringP(START_LONG_OPTION, START_SHORT_OPTION, "", "Board to play, e.g. 0,1,2/4,6,3/7,5,8. Defaults to a random board")
+	PlayCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	PlayCmd.Flags().Int(SIZE_LONG_OPTION, 3, "Width of a random board")
+	PlayCmd.Flags().Int(MOVES_LONG_OPTION, 0, "Scramble the target with this many random moves instead of picking any solvable board")
+	PlayCmd.Flags().Uint64(SEED_LONG_OPTION, 0, "Seed for the random board. 0 picks one from the clock")
+	PlayCmd.Flags().StringP(CONVENTION_LONG_OPTION, CONVENTION_SHORT_OPTION, square.BLANK_MOVES.String(), "Whether keys move the blank (blank) or the tile that slides into it (tile)")
+	PlayCmd.Flags().Duration(HINT_TIMEOUT_LONG_OPTION, 10*time.Second, "Give up on a hint after this long. 0 means no limit")
+}
@@ -22,7 +22,15 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"mysticsquare/cmd/analyze"
+	"mysticsquare/cmd/benchmark"
+	"mysticsquare/cmd/generate"
+	"mysticsquare/cmd/pdb"
+	"mysticsquare/cmd/play"
 	"mysticsquare/cmd/run"
+	"mysticsquare/cmd/serve"
+	"mysticsquare/cmd/table"
+	"mysticsquare/cmd/verify"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -43,6 +51,14 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.AddCommand(run.RunCmd)
+	rootCmd.AddCommand(pdb.PdbCmd)
+	rootCmd.AddCommand(generate.GenerateCmd)
+	rootCmd.AddCommand(benchmark.BenchmarkCmd)
+	rootCmd.AddCommand(play.PlayCmd)
+	rootCmd.AddCommand(verify.VerifyCmd)
+	rootCmd.AddCommand(analyze.AnalyzeCmd)
+	rootCmd.AddCommand(table.TableCmd)
+	rootCmd.AddCommand(serve.ServeCmd)
 }
 
 func initConfig() {
@@ -0,0 +1,265 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package run
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"mysticsquare/solver"
+	"mysticsquare/square"
+)
+
+// a single puzzle read from the batch input
+type batchJob struct {
+	index int
+	line  int
+	text  string
+}
+
+// result of a single puzzle in batch mode
+type BatchRecord struct {
+	Line  int    `json:"line"`
+	Error string `json:"error,omitempty"`
+	*solver.Report
+
+	index int
+}
+
+// totals over every puzzle of a batch
+type BatchSummary struct {
+	Puzzles         int           `json:"puzzles"`
+	Solved          int           `json:"solved"`
+	Unsolvable      int           `json:"unsolvable"`
+	NoPath          int           `json:"no_path"`
+	Cancelled       int           `json:"cancelled"`
+	BudgetExceeded  int           `json:"budget_exceeded"`
+	Errors          int           `json:"errors"`
+	TotalMoves      int           `json:"total_moves"`
+	LongestSolution int           `json:"longest_solution"`
+	NodesExpanded   int           `json:"nodes_expanded"`
+	SearchTime      time.Duration `json:"search_time_ns"`
+	WallTime        time.Duration `json:"wall_time_ns"`
+	AverageLength   float64       `json:"average_length"`
+	PuzzlesPerSec   float64       `json:"puzzles_per_second"`
+	WorkersUsed     int           `json:"workers"`
+	OrderPreserved  bool          `json:"ordered"`
+}
+
+// solve a single batch puzzle
+func (args CliArgs) solveBatchJob(ctx context.Context, job batchJob) (record *BatchRecord) {
+	record = &BatchRecord{Line: job.line, index: job.index}
+	initialSquare, targetSquare, err := square.ParsePuzzle(job.text)
+	if err == nil {
+		solved, solveErr := solver.Solve(ctx, initialSquare, targetSquare, args.options())
+		if err = args.solveError(solveErr); err == nil {
+			record.Report = solver.NewReport(initialSquare, targetSquare, args.options(), args.convention, solved)
+		}
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return
+}
+
+// add a record to the totals
+func (summary *BatchSummary) add(record *BatchRecord) {
+	summary.Puzzles++
+	switch {
+	case record.Report == nil:
+		summary.Errors++
+	case !record.Solvable:
+		summary.Unsolvable++
+	case record.Outcome == solver.CANCELLED_OUTCOME:
+		summary.Cancelled++
+	case record.Outcome == solver.BUDGET_EXCEEDED_OUTCOME:
+		summary.BudgetExceeded++
+	case !record.PathFound:
+		summary.NoPath++
+	default:
+		summary.Solved++
+		summary.TotalMoves += record.SolutionLength
+		summary.LongestSolution = max(summary.LongestSolution, record.SolutionLength)
+	}
+	if record.Report != nil && record.Statistics != nil {
+		summary.NodesExpanded += record.Statistics.NodesExpanded
+		summary.SearchTime += record.Statistics.WallTime
+	}
+}
+
+// write a record in the given format. Json records are written one per line.
+func (record *BatchRecord) Write(w io.Writer, format string) (err error) {
+	if format == JSON_OUTPUT {
+		err = json.NewEncoder(w).Encode(record)
+		return
+	}
+
+	switch {
+	case record.Report == nil:
+		_, err = fmt.Fprintf(w, "%v\terror\t-\t%v\n", record.Line, record.Error)
+	case !record.Solvable:
+		_, err = fmt.Fprintf(w, "%v\tunsolvable\t-\t%v\n", record.Line, record.Reason)
+	case record.Outcome == solver.CANCELLED_OUTCOME:
+		_, err = fmt.Fprintf(w, "%v\tcancelled\t-\t%v\n", record.Line, record.Reason)
+	case record.Outcome == solver.BUDGET_EXCEEDED_OUTCOME:
+		_, err = fmt.Fprintf(w, "%v\tbudget exceeded\t-\t%v\n", record.Line, record.Reason)
+	case !record.PathFound:
+		_, err = fmt.Fprintf(w, "%v\tno path\t-\t-\n", record.Line)
+	default:
+		_, err = fmt.Fprintf(w, "%v\tsolved\t%v\t%v\n", record.Line, record.SolutionLength, record.MoveString)
+	}
+	return
+}
+
+// write the summary in the given format
+func (summary BatchSummary) Write(w io.Writer, format string) (err error) {
+	if format == JSON_OUTPUT {
+		err = json.NewEncoder(w).Encode(map[string]BatchSummary{"summary": summary})
+		return
+	}
+
+	_, err = fmt.Fprintf(w, `SUMMARY
+puzzles:          %v
+solved:           %v
+unsolvable:       %v
+no path:          %v
+cancelled:        %v
+budget exceeded:  %v
+errors:           %v
+total moves:      %v
+average length:   %.2f
+longest solution: %v
+nodes expanded:   %v
+search time:      %v
+wall time:        %v
+puzzles/second:   %.2f
+`, summary.Puzzles, summary.Solved, summary.Unsolvable, summary.NoPath, summary.Cancelled, summary.BudgetExceeded, summary.Errors, summary.TotalMoves, summary.AverageLength, summary.LongestSolution, summary.NodesExpanded, summary.SearchTime, summary.WallTime, summary.PuzzlesPerSec)
+	return
+}
+
+// open the batch input named by --batch
+func (args CliArgs) batchInput() (input io.Reader, closeInput func() error, err error) {
+	closeInput = func() error { return nil }
+	if args.batch == STDIN_BOARD {
+		if input = args.input; input == nil {
+			err = fmt.Errorf("no input available to read puzzles from")
+		}
+		return
+	}
+	file, openErr := os.Open(args.batch)
+	if openErr != nil {
+		err = openErr
+		return
+	}
+	input, closeInput = file, file.Close
+	return
+}
+
+// solve every puzzle of the batch input on a pool of workers
+func executeBatch(ctx context.Context, args *CliArgs, w io.Writer) (err error) {
+	input, closeInput, inputErr := args.batchInput()
+	if inputErr != nil {
+		err = inputErr
+		return
+	}
+	defer closeInput()
+
+	started := time.Now()
+	jobs := make(chan batchJob)
+	records := make(chan *BatchRecord)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(input)
+		index := 0
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			jobs <- batchJob{index: index, line: line, text: text}
+			index++
+		}
+		readErr = scanner.Err()
+	}()
+
+	var workers sync.WaitGroup
+	for worker := 0; worker < args.workers; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				records <- args.solveBatchJob(ctx, job)
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(records)
+	}()
+
+	summary := BatchSummary{WorkersUsed: args.workers, OrderPreserved: args.ordered}
+	pending := make(map[int]*BatchRecord)
+	next := 0
+	for record := range records {
+		summary.add(record)
+		if !args.ordered {
+			if writeErr := record.Write(w, args.output); writeErr != nil && err == nil {
+				err = writeErr
+			}
+			continue
+		}
+		pending[record.index] = record
+		for ready, exists := pending[next]; exists; ready, exists = pending[next] {
+			if writeErr := ready.Write(w, args.output); writeErr != nil && err == nil {
+				err = writeErr
+			}
+			delete(pending, next)
+			next++
+		}
+	}
+	if err != nil {
+		return
+	}
+	if readErr != nil {
+		err = readErr
+		return
+	}
+
+	summary.WallTime = time.Since(started)
+	if summary.Solved > 0 {
+		summary.AverageLength = float64(summary.TotalMoves) / float64(summary.Solved)
+	}
+	if seconds := summary.WallTime.Seconds(); seconds > 0 {
+		summary.PuzzlesPerSec = float64(summary.Puzzles) / seconds
+	}
+	err = summary.Write(w, args.output)
+	return
+}
@@ -0,0 +1,99 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"mysticsquare/solver"
+)
+
+// output format constants
+const (
+	TEXT_OUTPUT  = "text"
+	JSON_OUTPUT  = "json"
+	MOVES_OUTPUT = "moves"
+)
+
+// write the report in the human readable format
+func writeReportText(w io.Writer, result *solver.Report) (err error) {
+	switch {
+	case !result.Solvable:
+		_, err = fmt.Fprintf(w, "Unsolvable: %v\n", result.Reason)
+	case result.Outcome == solver.CANCELLED_OUTCOME:
+		_, err = fmt.Fprintf(w, "Cancelled: %v\n", result.Reason)
+	case result.Outcome == solver.BUDGET_EXCEEDED_OUTCOME:
+		_, err = fmt.Fprintf(w, "Budget exceeded: %v\n", result.Reason)
+	case !result.PathFound:
+		_, err = fmt.Fprintln(w, "No Path")
+	default:
+		if len(result.Thresholds) > 0 {
+			fmt.Fprintf(w, "IDA* thresholds: %v\n", result.Thresholds)
+		}
+		fmt.Fprintln(w, "START")
+		for _, current := range result.Path {
+			fmt.Fprintln(w, current.State())
+			_, err = fmt.Fprintln(w)
+		}
+	}
+	return
+}
+
+// write the solution as a single line of move notation
+func writeReportMoves(w io.Writer, result *solver.Report) (err error) {
+	switch {
+	case !result.Solvable:
+		_, err = fmt.Fprintf(w, "Unsolvable: %v\n", result.Reason)
+	case result.Outcome == solver.CANCELLED_OUTCOME:
+		_, err = fmt.Fprintf(w, "Cancelled: %v\n", result.Reason)
+	case result.Outcome == solver.BUDGET_EXCEEDED_OUTCOME:
+		_, err = fmt.Fprintf(w, "Budget exceeded: %v\n", result.Reason)
+	case !result.PathFound:
+		_, err = fmt.Fprintln(w, "No Path")
+	default:
+		_, err = fmt.Fprintln(w, result.MoveString)
+	}
+	return
+}
+
+// write the report as a single json document
+func writeReportJson(w io.Writer, result *solver.Report) (err error) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(result)
+	return
+}
+
+// write the report in the given format
+func writeReport(w io.Writer, result *solver.Report, format string) (err error) {
+	switch format {
+	case JSON_OUTPUT:
+		err = writeReportJson(w, result)
+	case MOVES_OUTPUT:
+		err = writeReportMoves(w, result)
+	default:
+		err = writeReportText(w, result)
+	}
+	return
+}
@@ -22,20 +22,27 @@ THE SOFTWARE.
 package run
 
 import (
-	"container/heap"
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"math"
-	"slices"
-
-	"mysticsquare/datastructures"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/lookup"
+	"mysticsquare/pdb"
+	"mysticsquare/solver"
 	"mysticsquare/square"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-type AlgorithmSelection int
 type SquareDifficulty int
 
 // difficulty constants
@@ -45,49 +52,158 @@ const (
 	NO_PATH         SquareDifficulty = 3
 )
 
-// algorithm constants
-const (
-	A_STAR_SEARCH        AlgorithmSelection = 1
-	DIJKSTRAS_ALGORITHM  AlgorithmSelection = 2
-	BREADTH_FIRST_SEARCH AlgorithmSelection = 3
-)
-
 // options constants
 const (
 	ALGORITHM_LONG_OPTION   = "algorithm"
 	ALGORITHM_SHORT_OPTION  = "a"
 	DIFFICULTY_LONG_OPTION  = "difficulty"
 	DIFFICULTY_SHORT_OPTION = "d"
+	START_LONG_OPTION       = "start"
+	START_SHORT_OPTION      = "s"
+	TARGET_LONG_OPTION      = "target"
+	TARGET_SHORT_OPTION     = "t"
+	HEURISTIC_LONG_OPTION   = "heuristic"
+	HEURISTIC_SHORT_OPTION  = "H"
+	PDB_LONG_OPTION         = "pdb"
+	PDB_SHORT_OPTION        = "p"
+	TABLE_LONG_OPTION       = "table"
+	OUTPUT_LONG_OPTION      = "output"
+	OUTPUT_SHORT_OPTION     = "o"
+	CONVENTION_LONG_OPTION  = "convention"
+	CONVENTION_SHORT_OPTION = "c"
+	STATS_LONG_OPTION       = "stats"
+	BATCH_LONG_OPTION       = "batch"
+	BATCH_SHORT_OPTION      = "b"
+	WORKERS_LONG_OPTION     = "workers"
+	WORKERS_SHORT_OPTION    = "w"
+	ORDERED_LONG_OPTION     = "ordered"
+	TIMEOUT_LONG_OPTION     = "timeout"
+	MAX_NODES_LONG_OPTION   = "max-nodes"
+	MAX_MEMORY_LONG_OPTION  = "max-memory"
+	OPEN_LIST_LONG_OPTION   = "open-list"
 )
 
+// board argument meaning "read the board from stdin"
+const STDIN_BOARD = "-"
+
 // cli args
 type CliArgs struct {
 	difficulty SquareDifficulty
-	algorithm  AlgorithmSelection
+	algorithm  solver.AlgorithmSelection
+	heuristic  solver.HeuristicSelection
+	start      string
+	target     string
+	pdbFile    string
+	db         *pdb.PatternDatabase
+	tableFile  string
+	table      *lookup.Table
+	output     string
+	convention square.MoveConvention
+	stats      bool
+	batch      string
+	workers    int
+	ordered    bool
+	timeout    time.Duration
+	budget     solver.SearchBudget
+	openList   string
+	input      io.Reader
 }
 
 // create a new set of Cli Args
-func NewRunCliArgs() (args *CliArgs, valid bool) {
+func NewRunCliArgs() (args *CliArgs, err error) {
 	args = &CliArgs{}
-	valid = true
+	args.start = viper.GetString(START_LONG_OPTION)
+	args.target = viper.GetString(TARGET_LONG_OPTION)
+	args.stats = viper.GetBool(STATS_LONG_OPTION)
+	args.batch = viper.GetString(BATCH_LONG_OPTION)
+	args.ordered = viper.GetBool(ORDERED_LONG_OPTION)
+	if args.timeout = viper.GetDuration(TIMEOUT_LONG_OPTION); args.timeout < 0 {
+		args = nil
+		err = fmt.Errorf("--%v can not be negative", TIMEOUT_LONG_OPTION)
+		return
+	}
+	if args.budget.MaxNodes = viper.GetInt(MAX_NODES_LONG_OPTION); args.budget.MaxNodes < 0 {
+		args = nil
+		err = fmt.Errorf("--%v can not be negative", MAX_NODES_LONG_OPTION)
+		return
+	}
+	if args.budget.MaxMemory, err = config.ParseByteSize(viper.GetString(MAX_MEMORY_LONG_OPTION)); err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", MAX_MEMORY_LONG_OPTION, err)
+		return
+	}
+	switch args.openList = viper.GetString(OPEN_LIST_LONG_OPTION); args.openList {
+	case solver.HEAP_OPEN_LIST, solver.BUCKET_OPEN_LIST:
+	default:
+		err = fmt.Errorf("--%v: unknown open list %q, expected %v or %v", OPEN_LIST_LONG_OPTION, args.openList, solver.HEAP_OPEN_LIST, solver.BUCKET_OPEN_LIST)
+		args = nil
+		return
+	}
+	if args.workers = viper.GetInt(WORKERS_LONG_OPTION); args.workers < 1 {
+		args = nil
+		err = fmt.Errorf("--%v must be at least 1", WORKERS_LONG_OPTION)
+		return
+	}
 	difficulty := SquareDifficulty(viper.GetInt(DIFFICULTY_LONG_OPTION))
-	switch difficulty {
-	case EASY_DIFFICULTY, HARD_DIFFICULTY, NO_PATH:
+	switch {
+	case difficulty == EASY_DIFFICULTY, difficulty == HARD_DIFFICULTY, difficulty == NO_PATH:
 		args.difficulty = difficulty
+	case args.start != "", args.batch != "":
+		args.difficulty = 0
 	default:
 		args = nil
-		valid = false
+		err = fmt.Errorf("give a --%v, --%v or --%v", DIFFICULTY_LONG_OPTION, START_LONG_OPTION, BATCH_LONG_OPTION)
 		return
 	}
 
-	algorithm := AlgorithmSelection(viper.GetInt(ALGORITHM_LONG_OPTION))
+	algorithm := solver.AlgorithmSelection(viper.GetInt(ALGORITHM_LONG_OPTION))
 
 	switch algorithm {
-	case A_STAR_SEARCH, DIJKSTRAS_ALGORITHM, BREADTH_FIRST_SEARCH:
+	case solver.A_STAR_SEARCH, solver.DIJKSTRAS_ALGORITHM, solver.BREADTH_FIRST_SEARCH, solver.IDA_STAR_SEARCH, solver.BIDIRECTIONAL_BFS:
 		args.algorithm = algorithm
+	case solver.LOOKUP_TABLE:
+		args.algorithm = algorithm
+		if args.tableFile = viper.GetString(TABLE_LONG_OPTION); args.tableFile == "" {
+			args = nil
+			err = fmt.Errorf("the lookup table algorithm needs --%v", TABLE_LONG_OPTION)
+			return
+		}
 	default:
 		args = nil
-		valid = false
+		err = fmt.Errorf("--%v: unknown algorithm %v", ALGORITHM_LONG_OPTION, int(algorithm))
+		return
+	}
+
+	switch output := viper.GetString(OUTPUT_LONG_OPTION); output {
+	case TEXT_OUTPUT, JSON_OUTPUT, MOVES_OUTPUT:
+		args.output = output
+	default:
+		args = nil
+		err = fmt.Errorf("--%v must be %v, %v or %v", OUTPUT_LONG_OPTION, TEXT_OUTPUT, JSON_OUTPUT, MOVES_OUTPUT)
+		return
+	}
+
+	if args.convention, err = square.ParseMoveConvention(viper.GetString(CONVENTION_LONG_OPTION)); err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", CONVENTION_LONG_OPTION, err)
+		return
+	}
+
+	heuristic := solver.HeuristicSelection(viper.GetInt(HEURISTIC_LONG_OPTION))
+
+	switch heuristic {
+	case solver.MANHATTAN_DISTANCE, solver.LINEAR_CONFLICT:
+		args.heuristic = heuristic
+	case solver.PATTERN_DATABASE:
+		args.heuristic = heuristic
+		if args.pdbFile = viper.GetString(PDB_LONG_OPTION); args.pdbFile == "" {
+			args = nil
+			err = fmt.Errorf("the pattern database heuristic needs --%v", PDB_LONG_OPTION)
+			return
+		}
+	default:
+		args = nil
+		err = fmt.Errorf("--%v: unknown heuristic %v", HEURISTIC_LONG_OPTION, int(heuristic))
 		return
 	}
 
@@ -145,12 +261,6 @@ func noPath() (initialState, targetState map[int]int) {
 	return
 }
 
-// description of algorithm parameter
-func algorithmDescription() (description string) {
-	description = fmt.Sprintf("Algorithm to use. A star: %v, Dijkstras: %v, BFS: %v", int(A_STAR_SEARCH), int(DIJKSTRAS_ALGORITHM), int(BREADTH_FIRST_SEARCH))
-	return
-}
-
 // description of difficulty parameter
 func difficultyDescription() (description string) {
 	description = fmt.Sprintf("Difficulty of the puzzle. Easy: %v, Hard: %v, No Path: %v", int(EASY_DIFFICULTY), int(HARD_DIFFICULTY), int(NO_PATH))
@@ -173,352 +283,152 @@ func (args CliArgs) squaresForDifficulty() (initialState, targetState map[int]in
 	return
 }
 
-// from the CliArgs return the algorithm to use
-func (args CliArgs) realAlgorithm() (algorithm func(is, ts square.MysticSquare) (paths map[string]square.MysticSquare, pathFound bool)) {
-	switch args.algorithm {
-	case A_STAR_SEARCH:
-		algorithm = func(is, ts square.MysticSquare) (paths map[string]square.MysticSquare, pathFound bool) {
-			paths, pathFound = aStar(is, ts, func(current square.MysticSquare) int { return manhattanDistance(current, ts) })
-			return
-		}
-	case DIJKSTRAS_ALGORITHM:
-		algorithm = dijkstrasAlgorithm
-	case BREADTH_FIRST_SEARCH:
-		algorithm = bfs
-	default:
-		algorithm = func(is, ts square.MysticSquare) (paths map[string]square.MysticSquare, pathFound bool) {
-			paths = make(map[string]square.MysticSquare)
-			pathFound = false
+// read the next non empty line from the input
+func readBoardLine(scanner *bufio.Scanner) (line string, err error) {
+	for scanner.Scan() {
+		if line = strings.TrimSpace(scanner.Text()); line != "" {
 			return
 		}
 	}
-	return
-}
-
-// implementation of manhattan distance
-func manhattanDistance(current, target square.MysticSquare) (distance int) {
-
-	if currentValid := current.ValidateState(); !currentValid {
-		panic(fmt.Sprintf("passed an invalid state to manhattan distance function: %v", current.RealState()))
-	}
-
-	if targetValid := target.ValidateState(); !targetValid {
-		panic(fmt.Sprintf("passed an invalid state to manhattan distance function: %v", target.RealState()))
-	}
-
-	if bothSquaresSameSize := len(current.RealState()) == len(target.RealState()); !bothSquaresSameSize {
-		panic(fmt.Sprintf("State size missmatch: %v, %v", current.RealState(), target.RealState()))
-	}
-
-	distance = 0
-
-	currentState := make([][]int, 0)
-	targetState := make([][]int, 0)
-	for i := 0; i < 3; i++ {
-		c1, c2, c3 := current.RealState()[1+3*i], current.RealState()[2+3*i], current.RealState()[3+3*i]
-		currentState = append(currentState, []int{c1, c2, c3})
-		t1, t2, t3 := target.RealState()[1+3*i], target.RealState()[2+3*i], target.RealState()[3+3*i]
-		targetState = append(targetState, []int{t1, t2, t3})
-	}
-
-	for currentRow := range currentState {
-		for currentColumn, currentValue := range currentState[currentRow] {
-			if targetValue := targetState[currentRow][currentColumn]; targetValue != currentValue && currentValue != 9 {
-				valueFound := false
-				for targetRow := range targetState {
-					if valueFound {
-						break
-					}
-					for targetColumn, searchTargetValue := range targetState[targetRow] {
-						if searchTargetValue == currentValue {
-							distance += int(math.Abs(float64(currentRow)-float64(targetRow)) + math.Abs(float64(currentColumn)-float64(targetColumn)))
-							valueFound = true
-							break
-						}
-					}
-
-				}
-			}
-		}
+	if err = scanner.Err(); err == nil {
+		err = fmt.Errorf("no board found on stdin")
 	}
 	return
 }
 
-// a* search implementation
-func aStar(initialState, targetState square.MysticSquare, h func(square.MysticSquare) int) (paths map[string]square.MysticSquare, pathFound bool) {
-	if h == nil {
-		panic("Invalid heuristic function")
-	}
-
-	q := datastructures.NewMysticSquarePriorityQueue()
-
-	paths = make(map[string]square.MysticSquare)
-	paths[initialState.State()] = nil
-
-	distance := make(map[string]int)
-	distance[initialState.State()] = 0
-
-	g := func(state square.MysticSquare) (g int) {
-		g = distance[state.State()]
-		return
-	}
-
-	f := func(state square.MysticSquare) (f int) {
-		detectOverflow := func(a, b int) (err error) {
-			ErrOverflow := errors.New("integer overflow detected")
-			if b > 0 {
-				if a > math.MaxInt-b {
-					err = ErrOverflow
-					return
-				}
-			}
-			err = nil
+// from CliArgs create the initial and target squares. Boards given with
+// --start and --target take precedence over the difficulty.
+func (args CliArgs) squares() (initialSquare, targetSquare square.MysticSquare, err error) {
+	if args.start == "" {
+		if args.target != "" {
+			err = fmt.Errorf("--%v requires --%v", TARGET_LONG_OPTION, START_LONG_OPTION)
 			return
 		}
-		gCurrent := g(state)
-		hCurrent := h(state)
-		if err := detectOverflow(gCurrent, hCurrent); err == nil {
-			f = gCurrent + hCurrent
-		} else {
-			f = math.MaxInt
+		initialState, targetState := args.squaresForDifficulty()
+		initialMysticSquare, initialErr := square.NewMysticSquare(initialState)
+		targetMysticSquare, targetErr := square.NewMysticSquare(targetState)
+		if initialErr != nil || targetErr != nil {
+			err = fmt.Errorf("initial or target states invalid: %v, %v", initialErr, targetErr)
+			return
 		}
-
+		initialSquare, targetSquare = initialMysticSquare, targetMysticSquare
 		return
 	}
 
-	initialItem := datastructures.NewMysticSquareItem(initialState, f(initialState))
-	itemsMap := make(map[string]*datastructures.MysticSquareItem)
-	itemsMap[initialItem.Msquare.State()] = initialItem
-	visited := make(map[string]bool)
-	q.Push(initialItem)
-
-	heap.Init(q)
-
-	pathFound = false
-	targetStateString := targetState.State()
-
-	for currentItem, itemExists := q.Process(); itemExists; currentItem, itemExists = q.Process() {
-		current := currentItem.Msquare
-		currentStateString := current.State()
-		if currentStateString == targetStateString {
-			pathFound = true
-			break
-		}
-		adjacent := make([]square.MysticSquare, 0)
-
-		if leftState := current.MoveLeft(); leftState != nil {
-			if leftSquare, err := square.NewMysticSquare(leftState); err == nil {
-				adjacent = append(adjacent, leftSquare)
-			}
-		}
-
-		if rightState := current.MoveRight(); rightState != nil {
-			if rightSquare, err := square.NewMysticSquare(rightState); err == nil {
-				adjacent = append(adjacent, rightSquare)
-			}
+	var scanner *bufio.Scanner
+	if args.start == STDIN_BOARD || args.target == STDIN_BOARD {
+		input := args.input
+		if input == nil {
+			err = fmt.Errorf("no input available to read boards from")
+			return
 		}
+		scanner = bufio.NewScanner(input)
+	}
 
-		if upState := current.MoveUp(); upState != nil {
-			if upSquare, err := square.NewMysticSquare(upState); err == nil {
-				adjacent = append(adjacent, upSquare)
+	readBoard := func(option, text string) (board square.MysticSquare, err error) {
+		if text == STDIN_BOARD {
+			if text, err = readBoardLine(scanner); err != nil {
+				err = fmt.Errorf("--%v: %v", option, err)
+				return
 			}
 		}
-
-		if downState := current.MoveDown(); downState != nil {
-			if downSquare, err := square.NewMysticSquare(downState); err == nil {
-				adjacent = append(adjacent, downSquare)
-			}
+		if board, err = square.ParseMysticSquare(text); err != nil {
+			err = fmt.Errorf("--%v: %v", option, err)
 		}
+		return
+	}
 
-		for _, neighbor := range adjacent {
-			neighborStateString := neighbor.State()
-
-			if _, distanceForNeighborExists := distance[neighborStateString]; !distanceForNeighborExists {
-				distance[neighborStateString] = math.MaxInt
-				newItem := datastructures.NewMysticSquareItem(neighbor, f(neighbor))
-				itemsMap[neighborStateString] = newItem
-				heap.Push(q, newItem)
-			}
+	if initialSquare, err = readBoard(START_LONG_OPTION, args.start); err != nil {
+		return
+	}
 
-			tentativeDistance := distance[currentStateString] + 1
-			neighborDistance := distance[neighborStateString]
-			if _, neighborVisited := visited[neighborStateString]; tentativeDistance < neighborDistance && !neighborVisited {
-				paths[neighborStateString] = current
-				distance[neighborStateString] = tentativeDistance
-				item := itemsMap[neighborStateString]
-				q.Update(item, f(neighbor))
-			}
-		}
-		visited[currentStateString] = true
+	if args.target == "" {
+		targetSquare, err = square.NewMysticSquare(square.SolvedState(initialSquare.Size()))
+	} else {
+		targetSquare, err = readBoard(TARGET_LONG_OPTION, args.target)
+	}
+	if err != nil {
+		initialSquare, targetSquare = nil, nil
 	}
 	return
 }
 
-// dijkstras algorithm implementation
-func dijkstrasAlgorithm(initialState, targetState square.MysticSquare) (paths map[string]square.MysticSquare, pathFound bool) {
-	q := datastructures.NewMysticSquarePriorityQueue()
-
-	paths = make(map[string]square.MysticSquare)
-	paths[initialState.State()] = nil
-
-	distance := make(map[string]int)
-	distance[initialState.State()] = 0
-
-	initialItem := datastructures.NewMysticSquareItem(initialState, distance[initialState.State()])
-	itemsMap := make(map[string]*datastructures.MysticSquareItem)
-	itemsMap[initialItem.Msquare.State()] = initialItem
-	q.Push(initialItem)
-
-	heap.Init(q)
-
-	pathFound = false
-	targetStateString := targetState.State()
-
-	for currentItem, itemExists := q.Process(); itemExists; currentItem, itemExists = q.Process() {
-
-		current := currentItem.Msquare
-		currentString := current.State()
-
-		if currentString == targetStateString {
-			pathFound = true
-			break
-		}
-
-		adjacent := make([]square.MysticSquare, 0)
-		if leftState := current.MoveLeft(); leftState != nil {
-			if leftSquare, err := square.NewMysticSquare(leftState); err == nil {
-				adjacent = append(adjacent, leftSquare)
-			}
-		}
-
-		if rightState := current.MoveRight(); rightState != nil {
-			if rightSquare, err := square.NewMysticSquare(rightState); err == nil {
-				adjacent = append(adjacent, rightSquare)
-			}
-		}
-
-		if upState := current.MoveUp(); upState != nil {
-			if upSquare, err := square.NewMysticSquare(upState); err == nil {
-				adjacent = append(adjacent, upSquare)
-			}
-		}
-
-		if downState := current.MoveDown(); downState != nil {
-			if downSquare, err := square.NewMysticSquare(downState); err == nil {
-				adjacent = append(adjacent, downSquare)
-			}
-		}
-
-		for _, value := range adjacent {
-			valueString := value.State()
-			if _, distanceExists := distance[valueString]; !distanceExists {
-				distance[valueString] = math.MaxInt
-				newItem := datastructures.NewMysticSquareItem(value, distance[valueString])
-				itemsMap[valueString] = newItem
-				heap.Push(q, newItem)
-			}
-
-			currentDistanceForValue := distance[valueString]
-			altDistance := distance[currentString] + 1
-			if altDistance < currentDistanceForValue {
-				paths[valueString] = current
-				distance[valueString] = altDistance
-				item := itemsMap[valueString]
-				q.Update(item, altDistance)
-			}
-		}
+// load the pattern database when the heuristic needs one
+func (args *CliArgs) loadPatternDatabase() (err error) {
+	if args.heuristic == solver.PATTERN_DATABASE && args.algorithm.Informed() && args.db == nil {
+		args.db, err = pdb.LoadFile(args.pdbFile)
 	}
-
 	return
 }
 
-// bfs implementation
-func bfs(initialState square.MysticSquare, targetState square.MysticSquare) (paths map[string]square.MysticSquare, pathFound bool) {
-	q := datastructures.NewMysticSquareQueue()
-	visited := make(map[string]bool)
-	paths = make(map[string]square.MysticSquare)
-	pathFound = false
-	q.Push(initialState)
-	paths[initialState.State()] = nil
-	visited[initialState.State()] = true
-
-	for current, hasItem := q.Process(); hasItem; current, hasItem = q.Process() {
-
-		if pathFound = (current.State() == targetState.State()); pathFound {
-			break
-		}
-
-		adjacent := make([]square.MysticSquare, 0)
-
-		if stateLeft := current.MoveLeft(); stateLeft != nil {
-			if squareStateLeft, err := square.NewMysticSquare(stateLeft); err == nil {
-				adjacent = append(adjacent, squareStateLeft)
-			}
-		}
-
-		if stateRight := current.MoveRight(); stateRight != nil {
-			if squareStateRight, err := square.NewMysticSquare(stateRight); err == nil {
-				adjacent = append(adjacent, squareStateRight)
-			}
-		}
-
-		if stateUp := current.MoveUp(); stateUp != nil {
-			if squareStateUp, err := square.NewMysticSquare(stateUp); err == nil {
-				adjacent = append(adjacent, squareStateUp)
-			}
-		}
-
-		if stateDown := current.MoveDown(); stateDown != nil {
-			if squareStateDown, err := square.NewMysticSquare(stateDown); err == nil {
-				adjacent = append(adjacent, squareStateDown)
-			}
-		}
-
-		for _, newSquare := range adjacent {
-			if _, newSquareVisited := visited[newSquare.State()]; !newSquareVisited {
-				q.Push(newSquare)
-				paths[newSquare.State()] = current
-				visited[newSquare.State()] = true
-			}
-		}
+// load the lookup table when the algorithm reads one
+func (args *CliArgs) loadLookupTable() (err error) {
+	if args.algorithm == solver.LOOKUP_TABLE && args.table == nil {
+		args.table, err = lookup.LoadFile(args.tableFile)
 	}
-	if !pathFound {
-		paths = nil
+	return
+}
+
+// options of the solver library matching the CliArgs
+func (args CliArgs) options() (options solver.Options) {
+	options = solver.Options{
+		Algorithm:       args.algorithm,
+		Heuristic:       args.heuristic,
+		OpenList:        args.openList,
+		PatternDatabase: args.db,
+		Table:           args.table,
+		Timeout:         args.timeout,
+		Budget:          args.budget,
 	}
+	return
+}
 
+// name the file an error from solver.Solve refers to when the pattern
+// database or lookup table was built for another target
+func (args CliArgs) solveError(err error) (named error) {
+	named = err
+	switch {
+	case errors.Is(err, solver.ErrWrongTarget) && args.algorithm == solver.LOOKUP_TABLE:
+		named = fmt.Errorf("lookup table %v was built for a different target", args.tableFile)
+	case errors.Is(err, solver.ErrWrongTarget):
+		named = fmt.Errorf("pattern database %v was built for a different target", args.pdbFile)
+	}
 	return
 }
 
 // work horse of the entire command
-func executeRun(args *CliArgs) (err error) {
+func executeRun(ctx context.Context, args *CliArgs) (err error) {
 	if args == nil {
 		err = fmt.Errorf("args not provided")
 		return
 	}
 
-	initialState, targetState := args.squaresForDifficulty()
-	initialMysticSquare, initialErr := square.NewMysticSquare(initialState)
-	targetMysticSquare, targetErr := square.NewMysticSquare(targetState)
+	if err = args.loadPatternDatabase(); err != nil {
+		return
+	}
+	if err = args.loadLookupTable(); err != nil {
+		return
+	}
 
-	if initialErr == nil && targetErr == nil {
-		algorithm := args.realAlgorithm()
-		if paths, pathFound := algorithm(initialMysticSquare, targetMysticSquare); pathFound {
-			path := make([]square.MysticSquare, 0)
-			for current := targetMysticSquare; paths[current.State()] != nil; current = paths[current.State()] {
-				path = append(path, current)
-			}
-			path = append(path, initialMysticSquare)
-			slices.Reverse(path)
-			fmt.Println("START")
-			for _, square := range path {
-				fmt.Println(square.State())
-				fmt.Println()
-			}
-		} else {
-			fmt.Println("No Path")
+	if args.batch != "" {
+		err = executeBatch(ctx, args, os.Stdout)
+		return
+	}
+
+	initialMysticSquare, targetMysticSquare, squaresErr := args.squares()
+
+	if squaresErr == nil {
+		solved, solveErr := solver.Solve(ctx, initialMysticSquare, targetMysticSquare, args.options())
+		if solveErr != nil {
+			err = args.solveError(solveErr)
+			return
+		}
+		result := solver.NewReport(initialMysticSquare, targetMysticSquare, args.options(), args.convention, solved)
+		if err = writeReport(os.Stdout, result, args.output); err == nil && args.stats && args.output != JSON_OUTPUT && result.Statistics != nil {
+			err = result.Statistics.Write(os.Stdout)
 		}
 	} else {
-		err = fmt.Errorf("initial or target states invalid: %v, %v", initialErr, targetErr)
+		err = squaresErr
 		return
 	}
 	return
@@ -527,26 +437,52 @@ func executeRun(args *CliArgs) (err error) {
 // RunCmd represents the run command
 var RunCmd = &cobra.Command{
 	Use:   "run",
-	Short: "Solve a 3x3 mystic square",
-	Long:  `Chose a difficulty and an algorithm to solve the problem`,
+	Short: "Solve an NxN mystic square",
+	Long: `Chose a difficulty and an algorithm to solve the problem.
+
+Instead of a difficulty a board can be given with --start and optionally
+--target. Boards are listed row by row with tiles separated by commas and
+rows by slashes, using 0 for the blank, e.g. 0,1,2/4,6,3/7,5,8. Pass - to
+read a board from stdin, one board per line. Without --target the solved
+board of the same size is used.
+
+With --batch every line of a file (or stdin with -) is solved on a pool of
+--workers goroutines. A line holds a start board, optionally followed by
+-> and a target board, e.g. 1,2,3/4,0,6/7,5,8 -> 1,2,3/4,5,6/7,8,0. Boards
+may contain spaces. Blank lines and lines starting with # are skipped. One
+record is written per puzzle, followed by a summary.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		if cliArgs, argsValid := NewRunCliArgs(); argsValid {
-			err = executeRun(cliArgs)
+		if cliArgs, argsErr := NewRunCliArgs(); argsErr == nil {
+			cliArgs.input = cmd.InOrStdin()
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			err = executeRun(ctx, cliArgs)
 		} else {
-			err = fmt.Errorf("args not valid")
+			err = argsErr
 		}
 		return
 	},
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
-	RunCmd.Flags().IntP(ALGORITHM_LONG_OPTION, ALGORITHM_SHORT_OPTION, 0, algorithmDescription())
+	RunCmd.Flags().IntP(ALGORITHM_LONG_OPTION, ALGORITHM_SHORT_OPTION, 0, config.AlgorithmDescription(TABLE_LONG_OPTION))
 	RunCmd.Flags().IntP(DIFFICULTY_LONG_OPTION, DIFFICULTY_SHORT_OPTION, 0, difficultyDescription())
-}
-
-func initConfig() {
-	viper.BindPFlags(RunCmd.InheritedFlags())
-	viper.BindPFlags(RunCmd.LocalFlags())
+	RunCmd.Flags().IntP(HEURISTIC_LONG_OPTION, HEURISTIC_SHORT_OPTION, int(solver.MANHATTAN_DISTANCE), config.HeuristicDescription(PDB_LONG_OPTION))
+	RunCmd.Flags().StringP(PDB_LONG_OPTION, PDB_SHORT_OPTION, "", "Pattern database file built with the pdb command")
+	RunCmd.Flags().String(TABLE_LONG_OPTION, "", "Lookup table file built with the table build command")
+	RunCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, TEXT_OUTPUT, fmt.Sprintf("Output format. One of %v, %v, %v", TEXT_OUTPUT, JSON_OUTPUT, MOVES_OUTPUT))
+	RunCmd.Flags().StringP(CONVENTION_LONG_OPTION, CONVENTION_SHORT_OPTION, "blank", "Move notation convention. U/D/L/R name the direction the blank moves (blank) or the tile slides (tile)")
+	RunCmd.Flags().Bool(STATS_LONG_OPTION, false, "Print search statistics after the solution. Always included in json output")
+	RunCmd.Flags().Duration(TIMEOUT_LONG_OPTION, 0, "Stop a search that runs longer than this, e.g. 30s. Applies to each puzzle in batch mode. 0 means no limit")
+	RunCmd.Flags().Int(MAX_NODES_LONG_OPTION, 0, "Stop a search after expanding this many nodes. 0 means no limit")
+	RunCmd.Flags().String(MAX_MEMORY_LONG_OPTION, "", "Stop a search once its open list, closed set and parent map hold roughly this much, e.g. 512MiB. Empty means no limit")
+	RunCmd.Flags().String(OPEN_LIST_LONG_OPTION, solver.HEAP_OPEN_LIST, fmt.Sprintf("Open list used by A star and Dijkstras. One of %v (binary heap), %v (array of buckets indexed by cost)", solver.HEAP_OPEN_LIST, solver.BUCKET_OPEN_LIST))
+	RunCmd.Flags().StringP(BATCH_LONG_OPTION, BATCH_SHORT_OPTION, "", "Solve every puzzle in a file, or - for stdin, one per line")
+	RunCmd.Flags().IntP(WORKERS_LONG_OPTION, WORKERS_SHORT_OPTION, runtime.NumCPU(), "Number of puzzles solved at the same time in batch mode")
+	RunCmd.Flags().Bool(ORDERED_LONG_OPTION, true, "Write batch records in input order. When false records are written as soon as each puzzle is solved")
+	RunCmd.Flags().StringP(START_LONG_OPTION, START_SHORT_OPTION, "", "Initial board, e.g. 0,1,2/4,6,3/7,5,8, or - to read it from stdin")
+	RunCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board in the same form as --start. Defaults to the solved board")
 }
@@ -0,0 +1,676 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/lookup"
+	"mysticsquare/pdb"
+	"mysticsquare/solver"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// version of the json responses other than solve, which returns a
+// solver.Report. Bumped when fields change meaning.
+const SERVE_SCHEMA_VERSION = 1
+
+// options constants
+const (
+	ADDRESS_LONG_OPTION          = "address"
+	TIMEOUT_LONG_OPTION          = "timeout"
+	SHUTDOWN_TIMEOUT_LONG_OPTION = "shutdown-timeout"
+	MAX_NODES_LONG_OPTION        = "max-nodes"
+	MAX_MEMORY_LONG_OPTION       = "max-memory"
+	PDB_LONG_OPTION              = "pdb"
+	PDB_SHORT_OPTION             = "p"
+	TABLE_LONG_OPTION            = "table"
+)
+
+// largest request body the server reads
+const MAX_REQUEST_BYTES = 1 << 20
+
+// most boards a single generate request returns
+const MAX_GENERATE_COUNT = 1000
+
+// widest board generate accepts
+const MAX_GENERATE_SIZE = 10
+
+// widest board solve, solvable and hint accept. Checking a board takes time
+// before any search limit applies, so wider boards are refused outright.
+const MAX_SOLVE_SIZE = 10
+
+// memory a single search may hold unless --max-memory says otherwise
+const DEFAULT_MAX_MEMORY = "1GiB"
+
+// widest board generate accepts in distance mode. The mode visits every board
+// within the distance, which is only cheap enough to do per request on small
+// boards.
+const MAX_DISTANCE_MODE_SIZE = 3
+
+// most random moves a walk mode generate request makes over all its boards
+const MAX_GENERATE_WALK_MOVES = 1 << 20
+
+// bounds on the work of a single generate request
+var generateLimits = square.GenerateLimits{
+	MaxSize:         MAX_GENERATE_SIZE,
+	MaxCount:        MAX_GENERATE_COUNT,
+	MaxWalkMoves:    MAX_GENERATE_WALK_MOVES,
+	MaxDistanceSize: MAX_DISTANCE_MODE_SIZE,
+}
+
+// serve cli args
+type ServeCliArgs struct {
+	address         string
+	timeout         time.Duration
+	shutdownTimeout time.Duration
+	budget          solver.SearchBudget
+	pdbFile         string
+	db              *pdb.PatternDatabase
+	tableFile       string
+	table           *lookup.Table
+	log             io.Writer
+}
+
+// a board in a request. Either the compact form read by run --start, e.g.
+// "1,2,3/4,5,6/7,8,0", or rows of tiles, e.g. [[1,2,3],[4,5,6],[7,8,0]].
+// 0 is the blank in both.
+type jsonBoard struct {
+	square square.MysticSquare
+}
+
+// read either form of a board
+func (board *jsonBoard) UnmarshalJSON(data []byte) (err error) {
+	var text string
+	if json.Unmarshal(data, &text) != nil {
+		var rows [][]int
+		if err = json.Unmarshal(data, &rows); err != nil {
+			err = fmt.Errorf("a board is a string such as \"1,2,3/4,5,6/7,8,0\" or rows of tiles such as [[1,2,3],[4,5,6],[7,8,0]]")
+			return
+		}
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			tiles := make([]string, 0, len(row))
+			for _, tile := range row {
+				tiles = append(tiles, strconv.Itoa(tile))
+			}
+			lines = append(lines, strings.Join(tiles, ","))
+		}
+		text = strings.Join(lines, "/")
+	}
+	board.square, err = square.ParseMysticSquare(text)
+	return
+}
+
+// an algorithm or heuristic in a request, given by name such as "idastar" or
+// by the number run --algorithm takes
+type jsonSelection string
+
+// read a selection given as a string or a number
+func (selection *jsonSelection) UnmarshalJSON(data []byte) (err error) {
+	var number int
+	if json.Unmarshal(data, &number) == nil {
+		*selection = jsonSelection(strconv.Itoa(number))
+		return
+	}
+	var text string
+	if err = json.Unmarshal(data, &text); err == nil {
+		*selection = jsonSelection(text)
+	}
+	return
+}
+
+// body of the solve and hint requests. Only start is required.
+type solveRequest struct {
+	Start      *jsonBoard    `json:"start"`
+	Target     *jsonBoard    `json:"target"`
+	Algorithm  jsonSelection `json:"algorithm"`
+	Heuristic  jsonSelection `json:"heuristic"`
+	OpenList   string        `json:"open_list"`
+	Convention string        `json:"convention"`
+	// how long the search may run, e.g. "5s". Capped by the server.
+	Timeout string `json:"timeout"`
+}
+
+// body of the solvable request
+type solvableRequest struct {
+	Start  *jsonBoard `json:"start"`
+	Target *jsonBoard `json:"target"`
+}
+
+// body of the generate request. Every field is optional.
+type generateRequest struct {
+	Size   int        `json:"size"`
+	Target *jsonBoard `json:"target"`
+	Mode   string     `json:"mode"`
+	Moves  *int       `json:"moves"`
+	Count  int        `json:"count"`
+	Seed   uint64     `json:"seed"`
+}
+
+// response of the solvable request
+type SolvableResult struct {
+	SchemaVersion int    `json:"schema_version"`
+	Start         string `json:"start"`
+	Target        string `json:"target"`
+	Solvable      bool   `json:"solvable"`
+	Reason        string `json:"reason"`
+}
+
+// response of the generate request
+type GenerateResult struct {
+	SchemaVersion int      `json:"schema_version"`
+	Mode          string   `json:"mode"`
+	Seed          uint64   `json:"seed"`
+	Target        string   `json:"target"`
+	Boards        []string `json:"boards"`
+}
+
+// response of the hint request
+type HintResult struct {
+	SchemaVersion int    `json:"schema_version"`
+	Start         string `json:"start"`
+	Target        string `json:"target"`
+	Outcome       string `json:"outcome"`
+	Reason        string `json:"reason"`
+	// first move of an optimal solution in the requested convention, empty
+	// when there is none
+	Move       string `json:"move"`
+	Direction  string `json:"direction"`
+	Convention string `json:"convention"`
+	Next       string `json:"next"`
+	// length of the solution the move starts. -1 when unknown.
+	Remaining int `json:"remaining"`
+}
+
+// body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// an error with the http status it is reported with
+type requestError struct {
+	status int
+	err    error
+}
+
+func (err *requestError) Error() string {
+	return err.err.Error()
+}
+
+// a request the client got wrong
+func badRequest(format string, a ...any) (err error) {
+	err = &requestError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+	return
+}
+
+// create a new set of serve Cli Args
+func NewServeCliArgs() (args *ServeCliArgs, err error) {
+	args = &ServeCliArgs{
+		address:         viper.GetString(ADDRESS_LONG_OPTION),
+		timeout:         viper.GetDuration(TIMEOUT_LONG_OPTION),
+		shutdownTimeout: viper.GetDuration(SHUTDOWN_TIMEOUT_LONG_OPTION),
+		pdbFile:         viper.GetString(PDB_LONG_OPTION),
+		tableFile:       viper.GetString(TABLE_LONG_OPTION),
+		log:             os.Stderr,
+	}
+	if args.timeout < 0 || args.shutdownTimeout < 0 {
+		args = nil
+		err = fmt.Errorf("--%v and --%v can not be negative", TIMEOUT_LONG_OPTION, SHUTDOWN_TIMEOUT_LONG_OPTION)
+		return
+	}
+	if args.budget.MaxNodes = viper.GetInt(MAX_NODES_LONG_OPTION); args.budget.MaxNodes < 0 {
+		args = nil
+		err = fmt.Errorf("--%v can not be negative", MAX_NODES_LONG_OPTION)
+		return
+	}
+	if args.budget.MaxMemory, err = config.ParseByteSize(viper.GetString(MAX_MEMORY_LONG_OPTION)); err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", MAX_MEMORY_LONG_OPTION, err)
+		return
+	}
+	if args.pdbFile != "" {
+		if args.db, err = pdb.LoadFile(args.pdbFile); err != nil {
+			args = nil
+			return
+		}
+	}
+	if args.tableFile != "" {
+		if args.table, err = lookup.LoadFile(args.tableFile); err != nil {
+			args = nil
+		}
+	}
+	return
+}
+
+// parse an algorithm given by name or number
+func parseAlgorithm(text jsonSelection) (algorithm solver.AlgorithmSelection, err error) {
+	for candidate := solver.A_STAR_SEARCH; candidate <= solver.LOOKUP_TABLE; candidate++ {
+		if string(text) == candidate.String() || string(text) == strconv.Itoa(int(candidate)) {
+			algorithm = candidate
+			return
+		}
+	}
+	err = badRequest("unknown algorithm %q", text)
+	return
+}
+
+// parse a heuristic given by name or number
+func parseHeuristic(text jsonSelection) (heuristic solver.HeuristicSelection, err error) {
+	for candidate := solver.MANHATTAN_DISTANCE; candidate <= solver.PATTERN_DATABASE; candidate++ {
+		if string(text) == candidate.String() || string(text) == strconv.Itoa(int(candidate)) {
+			heuristic = candidate
+			return
+		}
+	}
+	err = badRequest("unknown heuristic %q", text)
+	return
+}
+
+// read the json body of a request into request
+func decodeRequest(w http.ResponseWriter, r *http.Request, request any) (err error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_BYTES))
+	decoder.DisallowUnknownFields()
+	if decodeErr := decoder.Decode(request); decodeErr != nil {
+		err = badRequest("invalid request body: %v", decodeErr)
+	}
+	return
+}
+
+// start and target of a request. The target defaults to the solved board of
+// the same size. Boards wider than MAX_SOLVE_SIZE are refused.
+func requestSquares(start, target *jsonBoard) (initialSquare, targetSquare square.MysticSquare, err error) {
+	if start == nil {
+		err = badRequest("start is required")
+		return
+	}
+	initialSquare = start.square
+	if initialSquare.Size() > MAX_SOLVE_SIZE || (target != nil && target.square.Size() > MAX_SOLVE_SIZE) {
+		err = badRequest("boards can be at most %vx%v", MAX_SOLVE_SIZE, MAX_SOLVE_SIZE)
+		return
+	}
+	if target != nil {
+		targetSquare = target.square
+	} else if targetSquare, err = square.NewMysticSquare(square.SolvedState(initialSquare.Size())); err != nil {
+		return
+	}
+	if initialSquare.Size() != targetSquare.Size() {
+		err = badRequest("start is %vx%v but target is %vx%v", initialSquare.Size(), initialSquare.Size(), targetSquare.Size(), targetSquare.Size())
+	}
+	return
+}
+
+// solver options and move convention of a solve or hint request within the
+// limits of the server. IDA star with linear conflict is used unless the
+// request says otherwise.
+func (args *ServeCliArgs) requestOptions(request *solveRequest) (options solver.Options, convention square.MoveConvention, err error) {
+	options = solver.Options{
+		Algorithm:       solver.IDA_STAR_SEARCH,
+		Heuristic:       solver.LINEAR_CONFLICT,
+		OpenList:        solver.HEAP_OPEN_LIST,
+		PatternDatabase: args.db,
+		Table:           args.table,
+		Timeout:         args.timeout,
+		Budget:          args.budget,
+	}
+	convention = square.BLANK_MOVES
+	if request.Algorithm != "" {
+		if options.Algorithm, err = parseAlgorithm(request.Algorithm); err != nil {
+			return
+		}
+	}
+	if request.Heuristic != "" {
+		if options.Heuristic, err = parseHeuristic(request.Heuristic); err != nil {
+			return
+		}
+	}
+	if options.Algorithm.Informed() && options.Heuristic == solver.PATTERN_DATABASE && args.db == nil {
+		err = badRequest("the server was started without --%v", PDB_LONG_OPTION)
+		return
+	}
+	if options.Algorithm == solver.LOOKUP_TABLE && args.table == nil {
+		err = badRequest("the server was started without --%v", TABLE_LONG_OPTION)
+		return
+	}
+	switch request.OpenList {
+	case "":
+	case solver.HEAP_OPEN_LIST, solver.BUCKET_OPEN_LIST:
+		options.OpenList = request.OpenList
+	default:
+		err = badRequest("unknown open list %q", request.OpenList)
+		return
+	}
+	if request.Convention != "" {
+		if convention, err = square.ParseMoveConvention(request.Convention); err != nil {
+			err = badRequest("%v", err)
+			return
+		}
+	}
+	if request.Timeout != "" {
+		timeout, parseErr := time.ParseDuration(request.Timeout)
+		if parseErr != nil || timeout <= 0 {
+			err = badRequest("timeout must be a positive duration such as 5s")
+			return
+		}
+		if args.timeout == 0 || timeout < args.timeout {
+			options.Timeout = timeout
+		}
+	}
+	return
+}
+
+// solve the board of a solve or hint request. convention is the one the
+// moves of the report are written in.
+func (args *ServeCliArgs) solveRequest(ctx context.Context, request *solveRequest) (report *solver.Report, convention square.MoveConvention, err error) {
+	initialSquare, targetSquare, err := requestSquares(request.Start, request.Target)
+	if err != nil {
+		return
+	}
+	options, convention, err := args.requestOptions(request)
+	if err != nil {
+		return
+	}
+	result, err := solver.Solve(ctx, initialSquare, targetSquare, options)
+	if err != nil {
+		err = &requestError{status: http.StatusUnprocessableEntity, err: err}
+		return
+	}
+	report = solver.NewReport(initialSquare, targetSquare, options, convention, result)
+	return
+}
+
+// POST /solve
+func (args *ServeCliArgs) handleSolve(r *http.Request, w http.ResponseWriter) (response any, err error) {
+	request := &solveRequest{}
+	if err = decodeRequest(w, r, request); err == nil {
+		response, _, err = args.solveRequest(r.Context(), request)
+	}
+	return
+}
+
+// POST /hint
+func (args *ServeCliArgs) handleHint(r *http.Request, w http.ResponseWriter) (response any, err error) {
+	request := &solveRequest{}
+	if err = decodeRequest(w, r, request); err != nil {
+		return
+	}
+	result, convention, err := args.solveRequest(r.Context(), request)
+	if err != nil {
+		return
+	}
+	hint := &HintResult{
+		SchemaVersion: SERVE_SCHEMA_VERSION,
+		Start:         result.Start,
+		Target:        result.Target,
+		Outcome:       result.Outcome,
+		Reason:        result.Reason,
+		Convention:    result.Convention,
+		Remaining:     -1,
+	}
+	if result.Outcome == solver.SOLVED_OUTCOME {
+		hint.Remaining = result.SolutionLength
+		if result.SolutionLength > 0 {
+			hint.Direction = result.Moves[0]
+			hint.Move = square.FormatMoves(result.Moves[:1], convention)
+			hint.Next = result.States[1]
+		}
+	}
+	response = hint
+	return
+}
+
+// POST /solvable
+func (args *ServeCliArgs) handleSolvable(r *http.Request, w http.ResponseWriter) (response any, err error) {
+	request := &solvableRequest{}
+	if err = decodeRequest(w, r, request); err != nil {
+		return
+	}
+	initialSquare, targetSquare, err := requestSquares(request.Start, request.Target)
+	if err != nil {
+		return
+	}
+	result := &SolvableResult{
+		SchemaVersion: SERVE_SCHEMA_VERSION,
+		Start:         square.FormatCompact(initialSquare),
+		Target:        square.FormatCompact(targetSquare),
+	}
+	result.Solvable, result.Reason = square.Solvable(initialSquare, targetSquare)
+	response = result
+	return
+}
+
+// POST /generate. Generation stops when the client goes away or after the
+// server --timeout.
+func (args *ServeCliArgs) handleGenerate(r *http.Request, w http.ResponseWriter) (response any, err error) {
+	request := &generateRequest{}
+	if err = decodeRequest(w, r, request); err != nil {
+		return
+	}
+	result := &GenerateResult{SchemaVersion: SERVE_SCHEMA_VERSION, Mode: request.Mode, Seed: request.Seed, Boards: make([]string, 0)}
+	if result.Mode == "" {
+		result.Mode = square.UNIFORM_MODE
+	}
+	if result.Seed == 0 {
+		result.Seed = uint64(time.Now().UnixNano())
+	}
+	count := request.Count
+	if count == 0 {
+		count = 1
+	}
+	moves := 20
+	if request.Moves != nil {
+		moves = *request.Moves
+	}
+
+	var target square.MysticSquare
+	if request.Target != nil {
+		target = request.Target.square
+	} else {
+		size := request.Size
+		if size == 0 {
+			size = 3
+		}
+		if err = generateLimits.CheckSize(size); err != nil {
+			err = badRequest("%v", err)
+			return
+		}
+		if target, err = square.NewMysticSquare(square.SolvedState(size)); err != nil {
+			return
+		}
+	}
+	result.Target = square.FormatCompact(target)
+
+	ctx := r.Context()
+	if args.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, args.timeout)
+		defer cancel()
+	}
+	rng := rand.New(rand.NewPCG(result.Seed, result.Seed))
+	boards, err := square.Generate(ctx, rng, target, result.Mode, moves, count, generateLimits)
+	var argsErr *square.GenerateArgsError
+	switch {
+	case err == nil:
+	case errors.As(err, &argsErr):
+		err = badRequest("%v", argsErr)
+		return
+	case ctx.Err() != nil:
+		err = &requestError{status: http.StatusServiceUnavailable, err: fmt.Errorf("generation stopped: %v", err)}
+		return
+	default:
+		err = &requestError{status: http.StatusUnprocessableEntity, err: err}
+		return
+	}
+	for _, board := range boards {
+		result.Boards = append(result.Boards, square.FormatCompact(board))
+	}
+	response = result
+	return
+}
+
+// write a json response with the given status
+func writeJsonResponse(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// adapt a handler returning its response to an http handler. Errors are
+// written as {"error": "..."}. Every request is logged with its status and
+// duration.
+func (args *ServeCliArgs) endpoint(handler func(r *http.Request, w http.ResponseWriter) (any, error)) (endpoint http.HandlerFunc) {
+	endpoint = func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		response, err := handler(r, w)
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusInternalServerError
+			var requestErr *requestError
+			if errors.As(err, &requestErr) {
+				status = requestErr.status
+			}
+			response = errorResponse{Error: err.Error()}
+		}
+		writeJsonResponse(w, status, response)
+		if args.log != nil {
+			fmt.Fprintf(args.log, "%v %v %v %v\n", r.Method, r.URL.Path, status, time.Since(started).Round(time.Microsecond))
+		}
+	}
+	return
+}
+
+// routes of the api
+func (args *ServeCliArgs) handler() (mux *http.ServeMux) {
+	mux = http.NewServeMux()
+	mux.Handle("POST /solve", args.endpoint(args.handleSolve))
+	mux.Handle("POST /solvable", args.endpoint(args.handleSolvable))
+	mux.Handle("POST /generate", args.endpoint(args.handleGenerate))
+	mux.Handle("POST /hint", args.endpoint(args.handleHint))
+	return
+}
+
+// work horse of the serve command. Serves until ctx is done, then stops
+// accepting connections and waits up to the shutdown timeout for requests
+// in flight.
+func executeServe(ctx context.Context, args *ServeCliArgs) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	listener, listenErr := net.Listen("tcp", args.address)
+	if listenErr != nil {
+		err = listenErr
+		return
+	}
+	server := &http.Server{Handler: args.handler(), ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	fmt.Fprintf(args.log, "listening on %v\n", listener.Addr())
+
+	select {
+	case err = <-served:
+		return
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(args.log, "shutting down")
+	shutdownCtx := context.Background()
+	if args.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, args.shutdownTimeout)
+		defer cancel()
+	}
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+	}
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return
+}
+
+// ServeCmd represents the serve command
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the solver as an HTTP JSON API",
+	Long: `Answer JSON requests over HTTP. Every endpoint takes a POST with a JSON body.
+
+  /solve     solve start into target. The response is the document written
+             by run --output json.
+  /solvable  check whether start can reach target
+  /generate  random solvable boards, as in the generate command. Requests
+             are bounded in size, count and walk moves and stop after
+             --timeout.
+  /hint      first move of an optimal solution
+
+Boards are given as "1,2,3/4,5,6/7,8,0" or as rows such as
+[[1,2,3],[4,5,6],[7,8,0]], with 0 for the blank. target defaults to the
+solved board. solve and hint take algorithm and heuristic by name or number
+(IDA star with linear conflict by default), open_list, convention and a
+timeout such as "5s" that can not exceed --timeout.
+
+Stops on SIGINT or SIGTERM after the requests in flight finish.`,
+	SilenceUsage: true,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewServeCliArgs(); argsErr == nil {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			err = executeServe(ctx, cliArgs)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	ServeCmd.Flags().String(ADDRESS_LONG_OPTION, ":8080", "Address to listen on")
+	ServeCmd.Flags().Duration(TIMEOUT_LONG_OPTION, 10*time.Second, "Longest a single search may run. Requests can ask for less. 0 means no limit")
+	ServeCmd.Flags().Duration(SHUTDOWN_TIMEOUT_LONG_OPTION, 30*time.Second, "How long to wait for requests in flight when stopping. 0 means no limit")
+	ServeCmd.Flags().Int(MAX_NODES_LONG_OPTION, 0, "Stop a search after expanding this many nodes. 0 means no limit")
+	ServeCmd.Flags().String(MAX_MEMORY_LONG_OPTION, DEFAULT_MAX_MEMORY, "Stop a search once it holds roughly this much, e.g. 512MiB. Empty means no limit")
+	ServeCmd.Flags().StringP(PDB_LONG_OPTION, PDB_SHORT_OPTION, "", "Pattern database file built with the pdb command, for the pattern-database heuristic")
+	ServeCmd.Flags().String(TABLE_LONG_OPTION, "", "Lookup table file built with the table build command, for the table algorithm")
+}
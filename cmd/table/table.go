@@ -0,0 +1,239 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package table
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/lookup"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// options constants
+const (
+	SIZE_LONG_OPTION    = "size"
+	SIZE_SHORT_OPTION   = "n"
+	TARGET_LONG_OPTION  = "target"
+	TARGET_SHORT_OPTION = "t"
+	OUTPUT_LONG_OPTION  = "output"
+	OUTPUT_SHORT_OPTION = "o"
+	INPUT_LONG_OPTION   = "input"
+	INPUT_SHORT_OPTION  = "i"
+	START_LONG_OPTION   = "start"
+	START_SHORT_OPTION  = "s"
+)
+
+// build cli args
+type BuildCliArgs struct {
+	target square.MysticSquare
+	output string
+}
+
+// inspect cli args
+type InspectCliArgs struct {
+	input string
+	start square.MysticSquare
+}
+
+// create a new set of build Cli Args
+func NewBuildCliArgs() (args *BuildCliArgs, err error) {
+	args = &BuildCliArgs{}
+	if args.output = viper.GetString(OUTPUT_LONG_OPTION); args.output == "" {
+		args = nil
+		err = fmt.Errorf("--%v is required", OUTPUT_LONG_OPTION)
+		return
+	}
+
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		if args.target, err = square.ParseMysticSquare(targetText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+		}
+		return
+	}
+
+	size := viper.GetInt(SIZE_LONG_OPTION)
+	if size < square.MIN_SIZE {
+		args = nil
+		err = fmt.Errorf("--%v must be at least %v", SIZE_LONG_OPTION, square.MIN_SIZE)
+		return
+	}
+	args.target, err = square.NewMysticSquare(square.SolvedState(size))
+	return
+}
+
+// create a new set of inspect Cli Args
+func NewInspectCliArgs() (args *InspectCliArgs, err error) {
+	args = &InspectCliArgs{}
+	if args.input = viper.GetString(INPUT_LONG_OPTION); args.input == "" {
+		args = nil
+		err = fmt.Errorf("--%v is required", INPUT_LONG_OPTION)
+		return
+	}
+	if startText := viper.GetString(START_LONG_OPTION); startText != "" {
+		if args.start, err = square.ParseMysticSquare(startText); err != nil {
+			args = nil
+			err = fmt.Errorf("--%v: %v", START_LONG_OPTION, err)
+		}
+	}
+	return
+}
+
+// work horse of the build command
+func executeBuild(args *BuildCliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	started := time.Now()
+	table, buildErr := lookup.NewTable(args.target)
+	if buildErr != nil {
+		err = buildErr
+		return
+	}
+	if err = table.SaveFile(args.output); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "%v states, diameter %v, built in %v\nsaved %v\n", table.States(), table.Diameter(), time.Since(started).Round(time.Millisecond), args.output)
+	return
+}
+
+// work horse of the inspect command
+func executeInspect(args *InspectCliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+
+	started := time.Now()
+	table, loadErr := lookup.LoadFile(args.input)
+	if loadErr != nil {
+		err = loadErr
+		return
+	}
+	loaded := time.Since(started)
+
+	var builder strings.Builder
+	totalDistance := uint64(0)
+	for distance, count := range table.Counts {
+		totalDistance += uint64(distance) * count
+	}
+	fmt.Fprintf(&builder, "File:             %v\n", args.input)
+	fmt.Fprintf(&builder, "Format:           %v version %v, checksum ok\n", lookup.FILE_MAGIC, lookup.FILE_VERSION)
+	fmt.Fprintf(&builder, "Size:             %vx%v\n", table.Size(), table.Size())
+	fmt.Fprintf(&builder, "Target:           %v\n", square.FormatCompact(table.Target))
+	fmt.Fprintf(&builder, "States:           %v\n", table.States())
+	fmt.Fprintf(&builder, "Diameter:         %v\n", table.Diameter())
+	fmt.Fprintf(&builder, "Average distance: %.3f\n", float64(totalDistance)/float64(table.States()))
+	fmt.Fprintf(&builder, "Loaded in:        %v\n", loaded.Round(time.Microsecond))
+
+	fmt.Fprintf(&builder, "\n%8v  %v\n", "distance", "states")
+	for distance, count := range table.Counts {
+		fmt.Fprintf(&builder, "%8v  %v\n", distance, count)
+	}
+
+	if args.start != nil {
+		started = time.Now()
+		path, solveErr := table.Solve(args.start)
+		if solveErr != nil {
+			err = solveErr
+			return
+		}
+		elapsed := time.Since(started)
+		directions := make([]string, 0, len(path)-1)
+		for idx := 1; idx < len(path); idx++ {
+			directions = append(directions, square.MoveBetween(path[idx-1], path[idx]))
+		}
+		fmt.Fprintf(&builder, "\nStart:            %v\n", square.FormatCompact(args.start))
+		fmt.Fprintf(&builder, "Distance:         %v\n", len(path)-1)
+		fmt.Fprintf(&builder, "Moves:            %v\n", square.FormatMoves(directions, square.BLANK_MOVES))
+		fmt.Fprintf(&builder, "Solved in:        %v\n", elapsed)
+	}
+	_, err = io.WriteString(w, builder.String())
+	return
+}
+
+// TableCmd represents the table command
+var TableCmd = &cobra.Command{
+	Use:   "table",
+	Short: "Build and inspect exact distance lookup tables",
+	Long: `A lookup table holds the exact distance to a target from every board that
+can reach it, two bits per board. run solves with it using --algorithm 6 and
+--table, walking straight to the target without searching.
+
+Tables cover 2x2 and 3x3 boards. A 3x3 table takes about 45KB.`,
+}
+
+// BuildCmd represents the table build command
+var BuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build a lookup table for a target board",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewBuildCliArgs(); argsErr == nil {
+			err = executeBuild(cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+// InspectCmd represents the table inspect command
+var InspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Check a lookup table file and print its header",
+	Long: `Load a lookup table, verify its checksum and counts and print the header.
+With --start the board is also solved from the table.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewInspectCliArgs(); argsErr == nil {
+			err = executeInspect(cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	BuildCmd.Flags().IntP(SIZE_LONG_OPTION, SIZE_SHORT_OPTION, 3, "Width of the board. Ignored when --target is given")
+	BuildCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	BuildCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, "", "File to save the lookup table to")
+	InspectCmd.Flags().StringP(INPUT_LONG_OPTION, INPUT_SHORT_OPTION, "", "Lookup table file built with table build")
+	InspectCmd.Flags().StringP(START_LONG_OPTION, START_SHORT_OPTION, "", "Board to solve from the table, e.g. 8,6,7/2,5,4/3,0,1")
+	TableCmd.AddCommand(BuildCmd)
+	TableCmd.AddCommand(InspectCmd)
+}
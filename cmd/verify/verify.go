@@ -0,0 +1,348 @@
+/*
+Copyright © 2024 Alex Helmacy
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"mysticsquare/cmd/config"
+	"mysticsquare/lookup"
+	"mysticsquare/pdb"
+	"mysticsquare/solver"
+	"mysticsquare/square"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// output format constants
+const (
+	TEXT_OUTPUT = "text"
+	JSON_OUTPUT = "json"
+)
+
+// version of the json output. Bumped when fields change meaning.
+const VERIFY_SCHEMA_VERSION = 1
+
+// options constants
+const (
+	START_LONG_OPTION       = "start"
+	START_SHORT_OPTION      = "s"
+	TARGET_LONG_OPTION      = "target"
+	TARGET_SHORT_OPTION     = "t"
+	MOVES_LONG_OPTION       = "moves"
+	MOVES_SHORT_OPTION      = "m"
+	CONVENTION_LONG_OPTION  = "convention"
+	CONVENTION_SHORT_OPTION = "c"
+	OUTPUT_LONG_OPTION      = "output"
+	OUTPUT_SHORT_OPTION     = "o"
+	OPTIMAL_LONG_OPTION     = "optimal"
+	ALGORITHM_LONG_OPTION   = "algorithm"
+	ALGORITHM_SHORT_OPTION  = "a"
+	HEURISTIC_LONG_OPTION   = "heuristic"
+	HEURISTIC_SHORT_OPTION  = "H"
+	PDB_LONG_OPTION         = "pdb"
+	PDB_SHORT_OPTION        = "p"
+	TABLE_LONG_OPTION       = "table"
+	TIMEOUT_LONG_OPTION     = "timeout"
+)
+
+// returned by verify when the moves do not take the start to the target
+var ErrVerificationFailed = errors.New("moves do not solve the board")
+
+// verify cli args
+type VerifyCliArgs struct {
+	start      square.MysticSquare
+	target     square.MysticSquare
+	moves      string
+	convention square.MoveConvention
+	output     string
+	optimal    bool
+	options    solver.Options
+	pdbFile    string
+	tableFile  string
+}
+
+// everything known about a checked move sequence. Serialized as the json
+// output.
+type VerifyResult struct {
+	SchemaVersion int    `json:"schema_version"`
+	Start         string `json:"start"`
+	Target        string `json:"target"`
+	Moves         string `json:"moves"`
+	Convention    string `json:"convention"`
+	Length        int    `json:"length"`
+	Legal         bool   `json:"legal"`
+	// position of the first illegal move, starting at 0. -1 when every
+	// move is legal.
+	IllegalIndex  int    `json:"illegal_index"`
+	IllegalMove   string `json:"illegal_move"`
+	Applied       int    `json:"applied"`
+	Final         string `json:"final"`
+	Reached       bool   `json:"reached"`
+	Valid         bool   `json:"valid"`
+	OptimalLength int    `json:"optimal_length"`
+	Optimal       bool   `json:"optimal"`
+	Excess        int    `json:"excess"`
+	OptimalMoves  string `json:"optimal_moves"`
+	// why the optimal length is unknown, empty when it is known
+	OptimalReason string `json:"optimal_reason"`
+}
+
+// create a new set of verify Cli Args
+func NewVerifyCliArgs() (args *VerifyCliArgs, err error) {
+	args = &VerifyCliArgs{moves: viper.GetString(MOVES_LONG_OPTION), output: viper.GetString(OUTPUT_LONG_OPTION), optimal: viper.GetBool(OPTIMAL_LONG_OPTION)}
+	if args.output != TEXT_OUTPUT && args.output != JSON_OUTPUT {
+		args = nil
+		err = fmt.Errorf("--%v must be %v or %v", OUTPUT_LONG_OPTION, TEXT_OUTPUT, JSON_OUTPUT)
+		return
+	}
+	if args.convention, err = square.ParseMoveConvention(viper.GetString(CONVENTION_LONG_OPTION)); err != nil {
+		args = nil
+		return
+	}
+
+	startText := viper.GetString(START_LONG_OPTION)
+	if startText == "" {
+		args = nil
+		err = fmt.Errorf("--%v is required", START_LONG_OPTION)
+		return
+	}
+	if args.start, err = square.ParseMysticSquare(startText); err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", START_LONG_OPTION, err)
+		return
+	}
+	if targetText := viper.GetString(TARGET_LONG_OPTION); targetText != "" {
+		args.target, err = square.ParseMysticSquare(targetText)
+	} else {
+		args.target, err = square.NewMysticSquare(square.SolvedState(args.start.Size()))
+	}
+	if err != nil {
+		args = nil
+		err = fmt.Errorf("--%v: %v", TARGET_LONG_OPTION, err)
+		return
+	}
+
+	args.options = solver.Options{
+		Algorithm: solver.AlgorithmSelection(viper.GetInt(ALGORITHM_LONG_OPTION)),
+		Heuristic: solver.HeuristicSelection(viper.GetInt(HEURISTIC_LONG_OPTION)),
+		OpenList:  solver.HEAP_OPEN_LIST,
+		Timeout:   viper.GetDuration(TIMEOUT_LONG_OPTION),
+	}
+	args.pdbFile, args.tableFile = viper.GetString(PDB_LONG_OPTION), viper.GetString(TABLE_LONG_OPTION)
+	if args.options.Algorithm.String() == "unknown" || args.options.Heuristic.String() == "unknown" {
+		args = nil
+		err = fmt.Errorf("unknown algorithm or heuristic")
+		return
+	}
+	if args.options.Heuristic == solver.PATTERN_DATABASE && args.pdbFile == "" {
+		args = nil
+		err = fmt.Errorf("the pattern database heuristic needs --%v", PDB_LONG_OPTION)
+		return
+	}
+	if args.options.Algorithm == solver.LOOKUP_TABLE && args.tableFile == "" {
+		args = nil
+		err = fmt.Errorf("the lookup table algorithm needs --%v", TABLE_LONG_OPTION)
+	}
+	return
+}
+
+// apply the moves to the start and compare the result with the target and,
+// when asked, with an optimal solution
+func (args *VerifyCliArgs) verify(ctx context.Context) (result *VerifyResult, err error) {
+	directions, parseErr := square.ParseMoves(args.moves, args.convention)
+	if parseErr != nil {
+		err = parseErr
+		return
+	}
+	result = &VerifyResult{
+		SchemaVersion: VERIFY_SCHEMA_VERSION,
+		Start:         square.FormatCompact(args.start),
+		Target:        square.FormatCompact(args.target),
+		Moves:         square.FormatMoves(directions, args.convention),
+		Convention:    args.convention.String(),
+		Length:        len(directions),
+		IllegalIndex:  -1,
+		OptimalLength: -1,
+		Excess:        -1,
+	}
+
+	path, replayErr := square.Replay(args.start, directions)
+	var illegal *square.IllegalMoveError
+	switch {
+	case errors.As(replayErr, &illegal):
+		result.IllegalIndex = illegal.Index
+		result.IllegalMove = square.FormatMoves([]string{illegal.Direction}, args.convention)
+	case replayErr != nil:
+		result = nil
+		err = replayErr
+		return
+	default:
+		result.Legal = true
+	}
+	final := path[len(path)-1]
+	result.Applied = len(path) - 1
+	result.Final = square.FormatCompact(final)
+	result.Reached = square.KeyOf(final) == square.KeyOf(args.target)
+	result.Valid = result.Legal && result.Reached
+
+	if !args.optimal {
+		result.OptimalReason = "not requested"
+		return
+	}
+	if args.options.Algorithm.Informed() && args.options.Heuristic == solver.PATTERN_DATABASE && args.options.PatternDatabase == nil {
+		if args.options.PatternDatabase, err = pdb.LoadFile(args.pdbFile); err != nil {
+			result = nil
+			return
+		}
+	}
+	if args.options.Algorithm == solver.LOOKUP_TABLE && args.options.Table == nil {
+		if args.options.Table, err = lookup.LoadFile(args.tableFile); err != nil {
+			result = nil
+			return
+		}
+	}
+	solved, solveErr := solver.Solve(ctx, args.start, args.target, args.options)
+	switch {
+	case solveErr != nil:
+		result.OptimalReason = solveErr.Error()
+	case solved.Outcome != solver.SOLVED_OUTCOME:
+		result.OptimalReason = solved.Outcome
+		if solved.Reason != "" {
+			result.OptimalReason = solved.Reason
+		}
+	default:
+		result.OptimalLength = solved.Cost
+		result.OptimalMoves = square.FormatMoves(solved.Moves, args.convention)
+		if result.Valid {
+			result.Excess = result.Length - result.OptimalLength
+			result.Optimal = result.Excess == 0
+		}
+	}
+	return
+}
+
+// write the result in the human readable format
+func (result *VerifyResult) writeText(w io.Writer) (err error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Moves:   %v (%v)\n", result.Length, result.Moves)
+	if !result.Legal {
+		fmt.Fprintf(&builder, "Illegal: move %v at index %v leaves the board after %v legal moves\n", result.IllegalMove, result.IllegalIndex, result.Applied)
+	}
+	fmt.Fprintf(&builder, "Final:   %v\n", result.Final)
+	fmt.Fprintf(&builder, "Target:  %v\n", result.Target)
+	if result.Reached {
+		fmt.Fprintln(&builder, "Reached: yes")
+	} else {
+		fmt.Fprintln(&builder, "Reached: no")
+	}
+	switch {
+	case result.OptimalLength < 0:
+		fmt.Fprintf(&builder, "Optimal: unknown, %v\n", result.OptimalReason)
+	case result.Optimal:
+		fmt.Fprintf(&builder, "Optimal: yes, %v moves\n", result.OptimalLength)
+	case result.Valid:
+		fmt.Fprintf(&builder, "Optimal: no, %v moves more than the optimal %v (%v)\n", result.Excess, result.OptimalLength, result.OptimalMoves)
+	default:
+		fmt.Fprintf(&builder, "Optimal: %v moves (%v)\n", result.OptimalLength, result.OptimalMoves)
+	}
+	if result.Valid {
+		fmt.Fprintln(&builder, "VALID")
+	} else {
+		fmt.Fprintln(&builder, "INVALID")
+	}
+	_, err = io.WriteString(w, builder.String())
+	return
+}
+
+// work horse of the verify command. Fails with ErrVerificationFailed when the
+// moves do not solve the board.
+func executeVerify(ctx context.Context, args *VerifyCliArgs, w io.Writer) (err error) {
+	if args == nil {
+		err = fmt.Errorf("args not provided")
+		return
+	}
+	result, err := args.verify(ctx)
+	if err != nil {
+		return
+	}
+	if args.output == JSON_OUTPUT {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	} else {
+		err = result.writeText(w)
+	}
+	if err == nil && !result.Valid {
+		err = ErrVerificationFailed
+	}
+	return
+}
+
+// VerifyCmd represents the verify command
+var VerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that a move string solves a mystic square",
+	Long: `Apply a move string to the start board and check it reaches the target.
+The first move that leaves the board is reported by its index, starting at 0.
+The length is compared with an optimal solution found by the solver unless
+--optimal=false is given.
+
+Exits with an error when the moves do not solve the board.`,
+	SilenceUsage: true,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		config.BindFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if cliArgs, argsErr := NewVerifyCliArgs(); argsErr == nil {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			err = executeVerify(ctx, cliArgs, os.Stdout)
+		} else {
+			err = argsErr
+		}
+		return
+	},
+}
+
+func init() {
+	VerifyCmd.Flags().StringP(START_LONG_OPTION, START_SHORT_OPTION, "", "Start board, e.g. 0,1,2/4,6,3/7,5,8")
+	VerifyCmd.Flags().StringP(TARGET_LONG_OPTION, TARGET_SHORT_OPTION, "", "Target board. Defaults to the solved board")
+	VerifyCmd.Flags().StringP(MOVES_LONG_OPTION, MOVES_SHORT_OPTION, "", "Moves to check, e.g. RDLU. Case, spaces and commas are ignored")
+	VerifyCmd.Flags().StringP(CONVENTION_LONG_OPTION, CONVENTION_SHORT_OPTION, square.BLANK_MOVES.String(), "Move notation convention. U/D/L/R name the direction the blank moves (blank) or the tile slides (tile)")
+	VerifyCmd.Flags().StringP(OUTPUT_LONG_OPTION, OUTPUT_SHORT_OPTION, TEXT_OUTPUT, fmt.Sprintf("Output format. One of %v, %v", TEXT_OUTPUT, JSON_OUTPUT))
+	VerifyCmd.Flags().Bool(OPTIMAL_LONG_OPTION, true, "Compare the length with an optimal solution")
+	VerifyCmd.Flags().IntP(ALGORITHM_LONG_OPTION, ALGORITHM_SHORT_OPTION, int(solver.IDA_STAR_SEARCH), "Algorithm used for the optimal solution. "+config.AlgorithmDescription(TABLE_LONG_OPTION))
+	VerifyCmd.Flags().IntP(HEURISTIC_LONG_OPTION, HEURISTIC_SHORT_OPTION, int(solver.LINEAR_CONFLICT), config.HeuristicDescription(PDB_LONG_OPTION))
+	VerifyCmd.Flags().StringP(PDB_LONG_OPTION, PDB_SHORT_OPTION, "", "Pattern database file built with the pdb command")
+	VerifyCmd.Flags().String(TABLE_LONG_OPTION, "", "Lookup table file built with the table build command")
+	VerifyCmd.Flags().Duration(TIMEOUT_LONG_OPTION, 30*time.Second, "Give up on the optimal solution after this long. 0 means no limit")
+}
@@ -0,0 +1,124 @@
+package datastructures
+
+import (
+	"fmt"
+)
+
+// order in which items with equal priority leave a BucketQueue
+type TieOrder int
+
+// tie order constants
+const (
+	FIRST_IN_FIRST_OUT TieOrder = 0
+	LAST_IN_FIRST_OUT  TieOrder = 1
+)
+
+// one bucket of a BucketQueue, a doubly linked list of items
+type bucketList[V, K any] struct {
+	head, tail *Item[V, K]
+}
+
+// priority queue for small non-negative integer priorities. Items are kept in
+// an array of lists indexed by priority, so pushing, removing the first item
+// and changing a key all take constant time apart from skipping empty
+// buckets. Items with equal priority leave in the given TieOrder.
+type BucketQueue[V, K any] struct {
+	buckets  []bucketList[V, K]
+	priority func(K) int
+	ties     TieOrder
+	lowest   int
+	length   int
+	sequence uint64
+}
+
+// create a new bucket queue. priority maps a key to its bucket and must not
+// be negative.
+func NewBucketQueue[V, K any](priority func(K) int, ties TieOrder) (pq *BucketQueue[V, K]) {
+	if priority == nil {
+		panic("Invalid priority function")
+	}
+	pq = &BucketQueue[V, K]{buckets: make([]bucketList[V, K], 0), priority: priority, ties: ties}
+	return
+}
+
+// check the len of the queue
+func (pq *BucketQueue[V, K]) Len() int {
+	return pq.length
+}
+
+// check if the queue is empty
+func (pq *BucketQueue[V, K]) Empty() bool {
+	return pq.Len() == 0
+}
+
+// append an item to the end of its bucket
+func (pq *BucketQueue[V, K]) link(item *Item[V, K]) {
+	bucket := pq.priority(item.key)
+	if bucket < 0 {
+		panic(fmt.Sprintf("negative priority %v", bucket))
+	}
+	for len(pq.buckets) <= bucket {
+		pq.buckets = append(pq.buckets, bucketList[V, K]{})
+	}
+	list := &pq.buckets[bucket]
+	item.bucket, item.prev, item.next = bucket, list.tail, nil
+	if list.tail != nil {
+		list.tail.next = item
+	} else {
+		list.head = item
+	}
+	list.tail = item
+	item.index = 0
+	pq.lowest = min(pq.lowest, bucket)
+	pq.length++
+}
+
+// take an item out of its bucket
+func (pq *BucketQueue[V, K]) unlink(item *Item[V, K]) {
+	list := &pq.buckets[item.bucket]
+	if item.prev != nil {
+		item.prev.next = item.next
+	} else {
+		list.head = item.next
+	}
+	if item.next != nil {
+		item.next.prev = item.prev
+	} else {
+		list.tail = item.prev
+	}
+	item.prev, item.next = nil, nil
+	item.index = -1
+	pq.length--
+}
+
+// add a value with the given key. The returned item can be passed to Update.
+func (pq *BucketQueue[V, K]) Push(value V, key K) (item *Item[V, K]) {
+	item = &Item[V, K]{Value: value, key: key, sequence: pq.sequence}
+	pq.sequence++
+	pq.link(item)
+	return
+}
+
+// check an item exists and remove the next one of the lowest bucket
+func (pq *BucketQueue[V, K]) Process() (current *Item[V, K], itemExists bool) {
+	if itemExists = !pq.Empty(); itemExists {
+		for pq.buckets[pq.lowest].head == nil {
+			pq.lowest++
+		}
+		if current = pq.buckets[pq.lowest].head; pq.ties == LAST_IN_FIRST_OUT {
+			current = pq.buckets[pq.lowest].tail
+		}
+		pq.unlink(current)
+	}
+	return
+}
+
+// change the key of an item still in the queue. The item moves to the end of
+// its new bucket.
+func (pq *BucketQueue[V, K]) Update(item *Item[V, K], key K) {
+	if item.index >= 0 {
+		pq.unlink(item)
+		item.key = key
+		pq.link(item)
+	}
+}
@@ -0,0 +1,166 @@
+package datastructures
+
+// an item of a generic priority queue. The key belongs to the queue; change
+// it with Update so the queue stays ordered.
+type Item[V, K any] struct {
+	Value    V
+	key      K
+	sequence uint64
+	index    int
+	// links used by BucketQueue
+	bucket     int
+	prev, next *Item[V, K]
+}
+
+// key the item is ordered by
+func (item *Item[V, K]) Key() K {
+	return item.key
+}
+
+// position of the item in the order items were pushed, starting at 0
+func (item *Item[V, K]) Sequence() uint64 {
+	return item.sequence
+}
+
+// priority queue whose items can change key after they were pushed.
+// Implemented by Heap and BucketQueue.
+type KeyedQueue[V, K any] interface {
+	Len() int
+	Empty() bool
+	Push(value V, key K) *Item[V, K]
+	Process() (*Item[V, K], bool)
+	Update(item *Item[V, K], key K)
+}
+
+// reports whether a leaves the queue before b
+type Less[V, K any] func(a, b *Item[V, K]) bool
+
+// order items by key, with compare returning a negative number when a comes
+// first, zero when the keys tie and a positive number otherwise as
+// cmp.Compare does. Items with equal keys leave in the order they were
+// pushed.
+func FirstInFirstOut[V, K any](compare func(a, b K) int) Less[V, K] {
+	return func(a, b *Item[V, K]) bool {
+		if order := compare(a.key, b.key); order != 0 {
+			return order < 0
+		}
+		return a.sequence < b.sequence
+	}
+}
+
+// order items by key as FirstInFirstOut does. Among items with equal keys the
+// last one pushed leaves first.
+func LastInFirstOut[V, K any](compare func(a, b K) int) Less[V, K] {
+	return func(a, b *Item[V, K]) bool {
+		if order := compare(a.key, b.key); order != 0 {
+			return order < 0
+		}
+		return a.sequence > b.sequence
+	}
+}
+
+// binary heap priority queue of values of type V ordered by keys of type K
+// with a pluggable comparator. Items are sifted by moving the hole rather than
+// swapping, so each level costs one comparison and one write.
+type Heap[V, K any] struct {
+	items    []*Item[V, K]
+	less     Less[V, K]
+	sequence uint64
+}
+
+// create a new heap ordered by less
+func NewHeap[V, K any](less Less[V, K]) (pq *Heap[V, K]) {
+	if less == nil {
+		panic("Invalid comparator")
+	}
+	pq = &Heap[V, K]{items: make([]*Item[V, K], 0), less: less}
+	return
+}
+
+// check the len of the heap
+func (pq *Heap[V, K]) Len() int {
+	return len(pq.items)
+}
+
+// check if the heap is empty
+func (pq *Heap[V, K]) Empty() bool {
+	return pq.Len() == 0
+}
+
+// place item at index or above it, moving the items it passes down
+func (pq *Heap[V, K]) up(item *Item[V, K], index int) {
+	for index > 0 {
+		parent := (index - 1) / 2
+		if !pq.less(item, pq.items[parent]) {
+			break
+		}
+		pq.items[index] = pq.items[parent]
+		pq.items[index].index = index
+		index = parent
+	}
+	pq.items[index] = item
+	item.index = index
+}
+
+// place item at index or below it, moving the items it passes up. Reports
+// whether the item moved.
+func (pq *Heap[V, K]) down(item *Item[V, K], index int) (moved bool) {
+	start := index
+	for n := len(pq.items); ; {
+		child := 2*index + 1
+		if child >= n {
+			break
+		}
+		if right := child + 1; right < n && pq.less(pq.items[right], pq.items[child]) {
+			child = right
+		}
+		if !pq.less(pq.items[child], item) {
+			break
+		}
+		pq.items[index] = pq.items[child]
+		pq.items[index].index = index
+		index = child
+	}
+	pq.items[index] = item
+	item.index = index
+	moved = index != start
+	return
+}
+
+// add a value with the given key. The returned item can be passed to Update.
+func (pq *Heap[V, K]) Push(value V, key K) (item *Item[V, K]) {
+	item = &Item[V, K]{Value: value, key: key, sequence: pq.sequence}
+	pq.sequence++
+	pq.items = append(pq.items, item)
+	pq.up(item, len(pq.items)-1)
+	return
+}
+
+// check an item exists and remove the first one in order
+func (pq *Heap[V, K]) Process() (current *Item[V, K], itemExists bool) {
+	if itemExists = !pq.Empty(); itemExists {
+		current = pq.items[0]
+		last := len(pq.items) - 1
+		moved := pq.items[last]
+		pq.items[last] = nil
+		pq.items = pq.items[:last]
+		if last > 0 {
+			pq.down(moved, 0)
+		}
+		current.index = -1
+	}
+	return
+}
+
+// change the key of an item still in the heap. Among equal keys the item then
+// counts as pushed last, as it does in a BucketQueue.
+func (pq *Heap[V, K]) Update(item *Item[V, K], key K) {
+	if item.index >= 0 {
+		item.key = key
+		item.sequence = pq.sequence
+		pq.sequence++
+		if !pq.down(item, item.index) {
+			pq.up(item, item.index)
+		}
+	}
+}
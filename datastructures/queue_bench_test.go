@@ -0,0 +1,94 @@
+package datastructures
+
+import (
+	"cmp"
+	"container/heap"
+	"math/rand/v2"
+	"testing"
+
+	"mysticsquare/square"
+)
+
+// number of items pushed, and popped, by one run of the benchmark workload
+const BENCHMARK_PUSHES = 1 << 16
+
+// priority increase of each child pushed by the workload. A search pops a
+// node and pushes its neighbors with an estimate a little above its own, so
+// the priorities stay in a narrow band and ties are common.
+var benchmarkDeltas = func() (deltas []int) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	deltas = make([]int, BENCHMARK_PUSHES)
+	for idx := range deltas {
+		deltas[idx] = rng.IntN(3)
+	}
+	return
+}()
+
+// run the workload shared by every queue benchmark: pop the best item and
+// push two children until BENCHMARK_PUSHES items were pushed, then drain the
+// queue. Fails when the priorities do not leave in order.
+func runQueueWorkload(b *testing.B, push func(priority int), pop func() (priority int, popped bool)) {
+	push(0)
+	pushes, pops := 1, 0
+	last := 0
+	for pushes < BENCHMARK_PUSHES || pops < pushes {
+		priority, popped := pop()
+		if !popped {
+			b.Fatalf("queue empty after %v pops of %v pushes", pops, pushes)
+		}
+		if priority < last {
+			b.Fatalf("popped priority %v after %v", priority, last)
+		}
+		last = priority
+		pops++
+		for child := 0; child < 2 && pushes < BENCHMARK_PUSHES; child++ {
+			push(priority + benchmarkDeltas[pushes])
+			pushes++
+		}
+	}
+}
+
+func BenchmarkBucketQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pq := NewBucketQueue[square.MysticSquare](func(priority int) int { return priority }, FIRST_IN_FIRST_OUT)
+		runQueueWorkload(b,
+			func(priority int) { pq.Push(nil, priority) },
+			func() (priority int, popped bool) {
+				item, popped := pq.Process()
+				if popped {
+					priority = item.Key()
+				}
+				return
+			})
+	}
+}
+
+func BenchmarkHeap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pq := NewHeap(FirstInFirstOut[square.MysticSquare](cmp.Compare[int]))
+		runQueueWorkload(b,
+			func(priority int) { pq.Push(nil, priority) },
+			func() (priority int, popped bool) {
+				item, popped := pq.Process()
+				if popped {
+					priority = item.Key()
+				}
+				return
+			})
+	}
+}
+
+func BenchmarkPriorityQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pq := NewMysticSquarePriorityQueue()
+		runQueueWorkload(b,
+			func(priority int) { heap.Push(pq, NewMysticSquareItem(nil, priority)) },
+			func() (priority int, popped bool) {
+				item, popped := pq.Process()
+				if popped {
+					priority = item.Priority()
+				}
+				return
+			})
+	}
+}
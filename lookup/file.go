@@ -0,0 +1,187 @@
+package lookup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"mysticsquare/square"
+)
+
+// lookup table file layout, all integers little endian:
+//
+//	magic    [4]byte "MSLT"
+//	version  uint16
+//	size     uint16
+//	target   size*size uint16, tile value for each position
+//	states   uint64, (size*size)!/2
+//	diameter uint16
+//	counts   diameter+1 uint64, number of states at each distance
+//	codes    (states+3)/4 bytes. Two bits per state, lowest bits first,
+//	         holding its distance modulo 3, indexed by square.ReachableRank
+//	checksum uint32 crc32 (IEEE) of everything before it
+const (
+	FILE_MAGIC   = "MSLT"
+	FILE_VERSION = 1
+)
+
+var ErrChecksum = errors.New("lookup table checksum mismatch")
+
+// write the table to w in the versioned binary format
+func (table *Table) Save(w io.Writer) (err error) {
+	buffered := bufio.NewWriter(w)
+	hash := crc32.NewIEEE()
+	out := io.MultiWriter(buffered, hash)
+
+	write := func(data any) {
+		if err == nil {
+			err = binary.Write(out, binary.LittleEndian, data)
+		}
+	}
+
+	write([]byte(FILE_MAGIC))
+	write(uint16(FILE_VERSION))
+	write(uint16(table.Size()))
+	for position := 1; position <= table.Size()*table.Size(); position++ {
+		write(uint16(table.Target.Tile(position)))
+	}
+	write(table.States())
+	write(uint16(table.Diameter()))
+	write(table.Counts)
+	write(table.codes)
+	if err == nil {
+		err = binary.Write(buffered, binary.LittleEndian, hash.Sum32())
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	return
+}
+
+// read a table written by Save
+func Load(r io.Reader) (table *Table, err error) {
+	hash := crc32.NewIEEE()
+	buffered := bufio.NewReader(r)
+	in := io.TeeReader(buffered, hash)
+
+	read := func(data any) {
+		if err == nil {
+			err = binary.Read(in, binary.LittleEndian, data)
+		}
+	}
+
+	magic := make([]byte, len(FILE_MAGIC))
+	var version, size, diameter uint16
+	var states uint64
+	read(magic)
+	read(&version)
+	if err == nil && string(magic) != FILE_MAGIC {
+		err = fmt.Errorf("not a lookup table file")
+	}
+	if err == nil && version != FILE_VERSION {
+		err = fmt.Errorf("unsupported lookup table version %v", version)
+	}
+	read(&size)
+	if err == nil && (int(size) < square.MIN_SIZE || int(size) > square.MAX_RANK_SIZE) {
+		err = fmt.Errorf("invalid board size %v", size)
+	}
+	if err != nil {
+		return
+	}
+
+	target := make(map[int]int)
+	for position := 1; position <= int(size)*int(size); position++ {
+		var value uint16
+		read(&value)
+		target[position] = int(value)
+	}
+	read(&states)
+	if err == nil && (states != square.ReachableCount(int(size)) || states > square.MAX_SWEEP_STATES) {
+		err = fmt.Errorf("table has %v states, expected %v", states, square.ReachableCount(int(size)))
+	}
+	read(&diameter)
+	if err != nil {
+		return
+	}
+
+	table = &Table{Counts: make([]uint64, int(diameter)+1), codes: make([]byte, codeBytes(states))}
+	read(table.Counts)
+	read(table.codes)
+
+	expected := hash.Sum32()
+	var checksum uint32
+	if err == nil {
+		err = binary.Read(buffered, binary.LittleEndian, &checksum)
+	}
+	if err == nil && checksum != expected {
+		err = ErrChecksum
+	}
+	if err == nil {
+		if table.Target, err = square.NewPackedSquare(target); err != nil {
+			err = fmt.Errorf("invalid target board: %v", err)
+		}
+	}
+	if err == nil {
+		err = table.validateCounts()
+	}
+	if err != nil {
+		table = nil
+	}
+	return
+}
+
+// ensure the number of codes of each value agrees with the counts
+func (table *Table) validateCounts() (err error) {
+	expected := make([]uint64, UNREACHED_CODE+1)
+	for distance, count := range table.Counts {
+		expected[distance%3] += count
+	}
+	if total := expected[0] + expected[1] + expected[2]; total != table.States() {
+		err = fmt.Errorf("counts add up to %v states, expected %v", total, table.States())
+		return
+	}
+	actual := make([]uint64, UNREACHED_CODE+1)
+	for rank := uint64(0); rank < table.States(); rank++ {
+		actual[table.codes[rank/CODES_PER_BYTE]>>(2*(rank%CODES_PER_BYTE))&3]++
+	}
+	for code := range expected {
+		if actual[code] != expected[code] {
+			err = fmt.Errorf("%v states have code %v, expected %v", actual[code], code, expected[code])
+			return
+		}
+	}
+	return
+}
+
+// write the table to a file
+func (table *Table) SaveFile(path string) (err error) {
+	file, createErr := os.Create(path)
+	if createErr != nil {
+		err = createErr
+		return
+	}
+	if err = table.Save(file); err != nil {
+		file.Close()
+		return
+	}
+	err = file.Close()
+	return
+}
+
+// read a table from a file
+func LoadFile(path string) (table *Table, err error) {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		err = openErr
+		return
+	}
+	defer file.Close()
+	if table, err = Load(file); err != nil {
+		err = fmt.Errorf("%v: %v", path, err)
+	}
+	return
+}
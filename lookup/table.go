@@ -0,0 +1,146 @@
+package lookup
+
+import (
+	"fmt"
+
+	"mysticsquare/square"
+)
+
+// states each byte of the table holds
+const CODES_PER_BYTE = 4
+
+// code stored for ranks no square reaching the target has
+const UNREACHED_CODE = 3
+
+// exact distance to a single target from every square that can reach it.
+//
+// Only the distance modulo 3 is stored, in two bits per state indexed by
+// square.ReachableRank. Every move changes the distance by exactly one, so
+// the neighbor one move closer is the one whose code is one less modulo 3
+// and walking those neighbors gives an optimal path and the distance itself.
+type Table struct {
+	Target square.PackedSquare
+	// number of states at each distance, starting at 0 for the target
+	Counts []uint64
+	codes  []byte
+}
+
+// sweep every square that can reach target and record its distance
+func NewTable(target square.MysticSquare) (table *Table, err error) {
+	packed, packErr := square.Pack(target)
+	if packErr != nil {
+		err = packErr
+		return
+	}
+	distances, sweepErr := square.ReachableDistances(packed, square.MAX_SWEEP_STATES)
+	if sweepErr != nil {
+		err = sweepErr
+		return
+	}
+
+	table = &Table{Target: packed, Counts: make([]uint64, 0), codes: make([]byte, codeBytes(uint64(len(distances))))}
+	for rank, distance := range distances {
+		for int(distance) >= len(table.Counts) {
+			table.Counts = append(table.Counts, 0)
+		}
+		table.Counts[distance]++
+		table.setCode(uint64(rank), int(distance)%3)
+	}
+	return
+}
+
+// bytes needed for the codes of the given number of states
+func codeBytes(states uint64) (count uint64) {
+	count = (states + CODES_PER_BYTE - 1) / CODES_PER_BYTE
+	return
+}
+
+// store the code of a rank
+func (table *Table) setCode(rank uint64, code int) {
+	shift := 2 * (rank % CODES_PER_BYTE)
+	table.codes[rank/CODES_PER_BYTE] &^= 3 << shift
+	table.codes[rank/CODES_PER_BYTE] |= byte(code) << shift
+}
+
+// distance modulo 3 of the square, or UNREACHED_CODE
+func (table *Table) code(current square.PackedSquare) (code int) {
+	rank, _ := square.ReachableRank(current)
+	code = int(table.codes[rank/CODES_PER_BYTE]>>(2*(rank%CODES_PER_BYTE))) & 3
+	return
+}
+
+// width of the board
+func (table *Table) Size() (size int) {
+	size = table.Target.Size()
+	return
+}
+
+// number of squares that can reach the target
+func (table *Table) States() (states uint64) {
+	states = square.ReachableCount(table.Size())
+	return
+}
+
+// largest distance of any square
+func (table *Table) Diameter() (diameter int) {
+	diameter = len(table.Counts) - 1
+	return
+}
+
+// check the table was built for target
+func (table *Table) Matches(target square.MysticSquare) (matches bool) {
+	matches = target != nil && target.Size() == table.Size() && square.KeyOf(target) == table.Target.Key()
+	return
+}
+
+// optimal path from start to the target, start first. Found by repeatedly
+// moving to the neighbor one move closer, so it never searches.
+func (table *Table) Solve(start square.MysticSquare) (path []square.MysticSquare, err error) {
+	if start == nil || start.Size() != table.Size() {
+		err = fmt.Errorf("the table is for %vx%v boards", table.Size(), table.Size())
+		return
+	}
+	if solvable, reason := square.Solvable(start, table.Target); !solvable {
+		err = fmt.Errorf("%v", reason)
+		return
+	}
+	current, packErr := square.Pack(start)
+	if packErr != nil {
+		err = packErr
+		return
+	}
+
+	path = []square.MysticSquare{current}
+	code := table.code(current)
+	for current != table.Target {
+		if code == UNREACHED_CODE || len(path) > len(table.Counts) {
+			path = nil
+			err = fmt.Errorf("corrupt table: no path from %v", square.FormatCompact(start))
+			return
+		}
+		closer := (code + 2) % 3
+		moved := false
+		for _, direction := range []string{square.LEFT, square.RIGHT, square.UP, square.DOWN} {
+			if next, legal := current.Step(direction); legal && table.code(next) == closer {
+				current, code, moved = next, closer, true
+				break
+			}
+		}
+		if !moved {
+			path = nil
+			err = fmt.Errorf("corrupt table: no neighbor of %v is closer", square.FormatCompact(current))
+			return
+		}
+		path = append(path, current)
+	}
+	return
+}
+
+// number of moves an optimal solution of start takes
+func (table *Table) Distance(start square.MysticSquare) (distance int, err error) {
+	path, err := table.Solve(start)
+	if err == nil {
+		distance = len(path) - 1
+	}
+	return
+}
@@ -0,0 +1,190 @@
+package pdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"mysticsquare/square"
+)
+
+// pattern database file layout, all integers little endian:
+//
+//	magic    [4]byte "MSPD"
+//	version  uint16
+//	size     uint16
+//	target   size*size uint16, tile value for each position
+//	patterns uint16
+//	for each pattern:
+//	  tiles     uint16, followed by that many uint16 tile values
+//	  entries   uint64, followed by that many uint8 distances
+//	checksum uint32 crc32 (IEEE) of everything before it
+const (
+	FILE_MAGIC   = "MSPD"
+	FILE_VERSION = 1
+)
+
+var ErrChecksum = errors.New("pattern database checksum mismatch")
+
+// write the database to w in the versioned binary format
+func (db *PatternDatabase) Save(w io.Writer) (err error) {
+	buffered := bufio.NewWriter(w)
+	hash := crc32.NewIEEE()
+	out := io.MultiWriter(buffered, hash)
+
+	write := func(data any) {
+		if err == nil {
+			err = binary.Write(out, binary.LittleEndian, data)
+		}
+	}
+
+	write([]byte(FILE_MAGIC))
+	write(uint16(FILE_VERSION))
+	write(uint16(db.Size))
+	for position := 1; position <= db.Size*db.Size; position++ {
+		write(uint16(db.Target[position]))
+	}
+	write(uint16(len(db.Patterns)))
+	for _, pattern := range db.Patterns {
+		write(uint16(len(pattern.Tiles)))
+		for _, tile := range pattern.Tiles {
+			write(uint16(tile))
+		}
+		write(uint64(len(pattern.Distances)))
+		write(pattern.Distances)
+	}
+	if err == nil {
+		err = binary.Write(buffered, binary.LittleEndian, hash.Sum32())
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	return
+}
+
+// read a database written by Save
+func Load(r io.Reader) (db *PatternDatabase, err error) {
+	hash := crc32.NewIEEE()
+	buffered := bufio.NewReader(r)
+	in := io.TeeReader(buffered, hash)
+
+	read := func(data any) {
+		if err == nil {
+			err = binary.Read(in, binary.LittleEndian, data)
+		}
+	}
+
+	magic := make([]byte, len(FILE_MAGIC))
+	var version, size, patternCount uint16
+	read(magic)
+	read(&version)
+	if err == nil && string(magic) != FILE_MAGIC {
+		err = fmt.Errorf("not a pattern database file")
+	}
+	if err == nil && version != FILE_VERSION {
+		err = fmt.Errorf("unsupported pattern database version %v", version)
+	}
+	read(&size)
+	if err == nil && (int(size) < square.MIN_SIZE || int(size) > MAX_SIZE) {
+		err = fmt.Errorf("invalid board size %v", size)
+	}
+	if err != nil {
+		return
+	}
+
+	db = &PatternDatabase{Size: int(size), Target: make(map[int]int), Patterns: make([]*Pattern, 0)}
+	for position := 1; position <= db.Size*db.Size; position++ {
+		var value uint16
+		read(&value)
+		db.Target[position] = int(value)
+	}
+	read(&patternCount)
+	for idx := 0; idx < int(patternCount) && err == nil; idx++ {
+		var tileCount uint16
+		var entries uint64
+		read(&tileCount)
+		if err != nil {
+			break
+		}
+		// bound the allocations by what the header allows before the
+		// checksum can be verified
+		expectedEntries, entriesErr := patternEntries(db.Size, int(tileCount))
+		if entriesErr != nil {
+			err = fmt.Errorf("pattern %v: %v", idx, entriesErr)
+			break
+		}
+		tiles16 := make([]uint16, tileCount)
+		read(tiles16)
+		read(&entries)
+		if err == nil && entries != expectedEntries {
+			err = fmt.Errorf("pattern %v has %v entries, expected %v", idx, entries, expectedEntries)
+		}
+		if err != nil {
+			break
+		}
+		pattern := &Pattern{Tiles: make([]int, tileCount), Distances: make([]uint8, entries)}
+		for tileIdx, tile := range tiles16 {
+			pattern.Tiles[tileIdx] = int(tile)
+		}
+		read(pattern.Distances)
+		db.Patterns = append(db.Patterns, pattern)
+	}
+
+	expected := hash.Sum32()
+	var checksum uint32
+	if err == nil {
+		err = binary.Read(buffered, binary.LittleEndian, &checksum)
+	}
+	if err == nil && checksum != expected {
+		err = ErrChecksum
+	}
+	if err == nil {
+		if _, targetErr := square.NewMysticSquare(db.Target); targetErr != nil {
+			err = fmt.Errorf("invalid target board: %v", targetErr)
+		}
+	}
+	if err == nil {
+		partition := make([][]int, 0, len(db.Patterns))
+		for _, pattern := range db.Patterns {
+			partition = append(partition, pattern.Tiles)
+		}
+		err = validatePartition(db.Size, partition)
+	}
+	if err != nil {
+		db = nil
+	}
+	return
+}
+
+// write the database to a file
+func (db *PatternDatabase) SaveFile(path string) (err error) {
+	file, createErr := os.Create(path)
+	if createErr != nil {
+		err = createErr
+		return
+	}
+	if err = db.Save(file); err != nil {
+		file.Close()
+		return
+	}
+	err = file.Close()
+	return
+}
+
+// read a database from a file
+func LoadFile(path string) (db *PatternDatabase, err error) {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		err = openErr
+		return
+	}
+	defer file.Close()
+	if db, err = Load(file); err != nil {
+		err = fmt.Errorf("%v: %v", path, err)
+	}
+	return
+}
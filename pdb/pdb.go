@@ -0,0 +1,322 @@
+package pdb
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mysticsquare/square"
+)
+
+// distance stored for abstract states that were never reached
+const UNREACHED = 255
+
+// widest board a pattern database may be built or loaded for
+const MAX_SIZE = 8
+
+// most entries a single pattern may hold. Each takes a byte, and building it
+// takes N*N times as many.
+const MAX_PATTERN_ENTRIES = 1 << 32
+
+// one pattern of an additive pattern database
+type Pattern struct {
+	Tiles     []int
+	Distances []uint8
+}
+
+// additive disjoint pattern database for a single target board
+type PatternDatabase struct {
+	Size     int
+	Target   map[int]int
+	Patterns []*Pattern
+}
+
+// parse a partition such as "1,2,3,4,5/6,7,8,9,10/11,12,13,14,15" into its
+// groups of tiles
+func ParsePartition(text string) (partition [][]int, err error) {
+	partition = make([][]int, 0)
+	for _, group := range strings.Split(text, "/") {
+		tiles := make([]int, 0)
+		for _, token := range strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' }) {
+			tile, convErr := strconv.Atoi(token)
+			if convErr != nil {
+				partition = nil
+				err = fmt.Errorf("tile is not a number: %q", token)
+				return
+			}
+			tiles = append(tiles, tile)
+		}
+		if len(tiles) > 0 {
+			partition = append(partition, tiles)
+		}
+	}
+	if len(partition) == 0 {
+		partition = nil
+		err = fmt.Errorf("empty partition")
+	}
+	return
+}
+
+// partition that splits every tile of the board into groups of at most
+// groupSize tiles in order
+func DefaultPartition(size, groupSize int) (partition [][]int) {
+	partition = make([][]int, 0)
+	for tile := 1; tile < square.BlankTile(size); tile += groupSize {
+		group := make([]int, 0, groupSize)
+		for t := tile; t < tile+groupSize && t < square.BlankTile(size); t++ {
+			group = append(group, t)
+		}
+		partition = append(partition, group)
+	}
+	return
+}
+
+// ensure the groups are disjoint and only contain tiles of the board
+func validatePartition(size int, partition [][]int) (err error) {
+	seen := make(map[int]bool)
+	for _, group := range partition {
+		if len(group) == 0 {
+			err = fmt.Errorf("empty pattern in partition")
+			return
+		}
+		for _, tile := range group {
+			if tile < 1 || tile >= square.BlankTile(size) {
+				err = fmt.Errorf("tile %v is not on a %vx%v board", tile, size, size)
+				return
+			}
+			if seen[tile] {
+				err = fmt.Errorf("tile %v appears in more than one pattern", tile)
+				return
+			}
+			seen[tile] = true
+		}
+	}
+	return
+}
+
+// number of entries of a pattern of k tiles on a board of the given width.
+// Fails when the pattern would hold more than MAX_PATTERN_ENTRIES.
+func patternEntries(size, k int) (entries uint64, err error) {
+	n := size * size
+	if k < 1 || k >= n {
+		err = fmt.Errorf("a pattern on a %vx%v board must have between 1 and %v tiles, not %v", size, size, n-1, k)
+		return
+	}
+	entries = 1
+	for i := 0; i < k; i++ {
+		if entries *= uint64(n - i); entries > MAX_PATTERN_ENTRIES {
+			entries = 0
+			err = fmt.Errorf("a pattern of %v tiles on a %vx%v board has more than %v entries", k, size, size, uint64(MAX_PATTERN_ENTRIES))
+			return
+		}
+	}
+	return
+}
+
+// number of ways to place k distinct tiles on n cells
+func placements(n, k int) (count int) {
+	count = 1
+	for i := 0; i < k; i++ {
+		count *= n - i
+	}
+	return
+}
+
+// dense index of the cells occupied by the tiles of a pattern. Each cell is
+// numbered among the cells not taken by the earlier tiles, giving a mixed
+// radix number with radix n, n-1, ...
+func rankPlacement(cells []int, n int) (rank int) {
+	rank = 0
+	for i, cell := range cells {
+		digit := cell
+		for _, earlier := range cells[:i] {
+			if earlier < cell {
+				digit--
+			}
+		}
+		rank = rank*(n-i) + digit
+	}
+	return
+}
+
+// inverse of rankPlacement
+func unrankPlacement(rank, n int, cells []int) {
+	k := len(cells)
+	for i := k - 1; i >= 0; i-- {
+		cells[i] = rank % (n - i)
+		rank /= n - i
+	}
+	used := make([]bool, n)
+	for i := range cells {
+		digit := cells[i]
+		for cell := 0; cell < n; cell++ {
+			if used[cell] {
+				continue
+			}
+			if digit == 0 {
+				cells[i] = cell
+				used[cell] = true
+				break
+			}
+			digit--
+		}
+	}
+}
+
+// build the distances of a single pattern with a backwards breadth-first
+// search from the abstract target. Only the pattern tiles and the blank are
+// tracked; moving a pattern tile costs one and moving any other tile is free,
+// which keeps the sum over disjoint patterns admissible.
+func buildPattern(size int, target map[int]int, tiles []int) (pattern *Pattern) {
+	n := size * size
+	blank := square.BlankTile(size)
+	count := placements(n, len(tiles))
+
+	targetCells := make([]int, len(tiles))
+	targetBlank := 0
+	for position, value := range target {
+		if value == blank {
+			targetBlank = position - 1
+		}
+		if idx := slices.Index(tiles, value); idx >= 0 {
+			targetCells[idx] = position - 1
+		}
+	}
+
+	// distances over pattern placement and blank cell
+	distance := make([]uint8, count*n)
+	for idx := range distance {
+		distance[idx] = UNREACHED
+	}
+
+	start := rankPlacement(targetCells, n)*n + targetBlank
+	distance[start] = 0
+	current := []int{start}
+	cells := make([]int, len(tiles))
+	occupant := make([]int, n)
+	for layer := 0; len(current) > 0; layer++ {
+		next := make([]int, 0)
+		for head := 0; head < len(current); head++ {
+			state := current[head]
+			if int(distance[state]) != layer {
+				continue
+			}
+			rank, blankCell := state/n, state%n
+			unrankPlacement(rank, n, cells)
+			for cell := range occupant {
+				occupant[cell] = -1
+			}
+			for idx, cell := range cells {
+				occupant[cell] = idx
+			}
+
+			row, column := blankCell/size, blankCell%size
+			for _, neighbor := range [4][2]int{{row - 1, column}, {row + 1, column}, {row, column - 1}, {row, column + 1}} {
+				if neighbor[0] < 0 || neighbor[0] >= size || neighbor[1] < 0 || neighbor[1] >= size {
+					continue
+				}
+				neighborCell := neighbor[0]*size + neighbor[1]
+				if tile := occupant[neighborCell]; tile >= 0 {
+					cells[tile] = blankCell
+					neighborState := rankPlacement(cells, n)*n + neighborCell
+					cells[tile] = neighborCell
+					if distance[neighborState] == UNREACHED {
+						distance[neighborState] = uint8(layer + 1)
+						next = append(next, neighborState)
+					}
+				} else {
+					neighborState := rank*n + neighborCell
+					if distance[neighborState] == UNREACHED || int(distance[neighborState]) > layer {
+						distance[neighborState] = uint8(layer)
+						current = append(current, neighborState)
+					}
+				}
+			}
+		}
+		current = next
+	}
+
+	pattern = &Pattern{Tiles: slices.Clone(tiles), Distances: make([]uint8, count)}
+	for rank := 0; rank < count; rank++ {
+		best := uint8(UNREACHED)
+		for blankCell := 0; blankCell < n; blankCell++ {
+			best = min(best, distance[rank*n+blankCell])
+		}
+		pattern.Distances[rank] = best
+	}
+	return
+}
+
+// build an additive pattern database for the target using the given
+// partition of the tiles
+func NewPatternDatabase(target square.MysticSquare, partition [][]int) (db *PatternDatabase, err error) {
+	if target == nil || !target.ValidateState() {
+		err = fmt.Errorf("invalid target square")
+		return
+	}
+	size := target.Size()
+	if size > MAX_SIZE {
+		err = fmt.Errorf("pattern databases support boards up to %vx%v", MAX_SIZE, MAX_SIZE)
+		return
+	}
+	if err = validatePartition(size, partition); err != nil {
+		return
+	}
+	for _, tiles := range partition {
+		if _, err = patternEntries(size, len(tiles)); err != nil {
+			return
+		}
+	}
+
+	db = &PatternDatabase{Size: size, Target: target.RealState(), Patterns: make([]*Pattern, 0, len(partition))}
+	for _, tiles := range partition {
+		db.Patterns = append(db.Patterns, buildPattern(size, db.Target, tiles))
+	}
+	return
+}
+
+// check the database was built for the target square
+func (db *PatternDatabase) Matches(target square.MysticSquare) (matches bool) {
+	if target == nil || target.Size() != db.Size {
+		matches = false
+		return
+	}
+	state := target.RealState()
+	matches = true
+	for position, value := range db.Target {
+		if state[position] != value {
+			matches = false
+			return
+		}
+	}
+	return
+}
+
+// sum of the pattern distances for the current square. Never overestimates
+// the number of moves to the target.
+func (db *PatternDatabase) Heuristic(current square.MysticSquare) (distance int) {
+	n := db.Size * db.Size
+	positions := make([]int, n+1)
+	if packed, ok := current.(square.PackedSquare); ok {
+		for position := 1; position <= n; position++ {
+			positions[packed.Tile(position)] = position - 1
+		}
+	} else {
+		for position, value := range current.RealState() {
+			positions[value] = position - 1
+		}
+	}
+
+	distance = 0
+	for _, pattern := range db.Patterns {
+		cells := make([]int, len(pattern.Tiles))
+		for idx, tile := range pattern.Tiles {
+			cells[idx] = positions[tile]
+		}
+		if value := pattern.Distances[rankPlacement(cells, n)]; value != UNREACHED {
+			distance += int(value)
+		}
+	}
+	return
+}
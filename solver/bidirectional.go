@@ -0,0 +1,117 @@
+package solver
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"mysticsquare/square"
+)
+
+// one side of a bidirectional search
+type searchFrontier struct {
+	frontier []square.MysticSquare
+	parents  map[square.StateKey]square.MysticSquare
+	distance map[square.StateKey]int
+}
+
+// create a frontier rooted at the given square
+func newSearchFrontier(root square.MysticSquare) (side *searchFrontier) {
+	side = &searchFrontier{
+		frontier: []square.MysticSquare{root},
+		parents:  map[square.StateKey]square.MysticSquare{square.KeyOf(root): nil},
+		distance: map[square.StateKey]int{square.KeyOf(root): 0},
+	}
+	return
+}
+
+// number of entries held by the frontier, distance and parent maps
+func (side *searchFrontier) entries() (count int) {
+	count = len(side.frontier) + len(side.distance) + len(side.parents)
+	return
+}
+
+// expand a whole layer of the frontier. Returns the meeting square with the
+// shortest combined distance to both roots among the squares generated in
+// this layer that the other side has already reached.
+func (side *searchFrontier) expandLayer(ctx context.Context, budget SearchBudget, other *searchFrontier, stats *SearchStatistics) (meeting square.MysticSquare, length int, err error) {
+	length = math.MaxInt
+	next := make([]square.MysticSquare, 0)
+	for _, current := range side.frontier {
+		if err = searchCancelled(ctx); err != nil {
+			return
+		}
+		if err = budget.check(stats, current.Size(), side.entries()+other.entries()+len(next)); err != nil {
+			return
+		}
+		currentDistance := side.distance[square.KeyOf(current)]
+		adjacent := adjacentSquares(current)
+		stats.NodesExpanded++
+		stats.NodesGenerated += len(adjacent)
+		for _, neighbor := range adjacent {
+			neighborKey := square.KeyOf(neighbor)
+			if _, seen := side.distance[neighborKey]; seen {
+				stats.DuplicatesSkipped++
+				continue
+			}
+			side.distance[neighborKey] = currentDistance + 1
+			side.parents[neighborKey] = current
+			next = append(next, neighbor)
+			if otherDistance, reached := other.distance[neighborKey]; reached && currentDistance+1+otherDistance < length {
+				meeting = neighbor
+				length = currentDistance + 1 + otherDistance
+			}
+		}
+	}
+	side.frontier = next
+	return
+}
+
+// bidirectional bfs implementation. Frontiers grow from both the initial and
+// the target square, always expanding the smaller one, until they meet.
+func bidirectionalBfs(ctx context.Context, budget SearchBudget, initialState, targetState square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+	started := time.Now()
+	defer func() { stats.WallTime = time.Since(started) }()
+
+	forward := newSearchFrontier(initialState)
+	backward := newSearchFrontier(targetState)
+	pathFound = false
+
+	var meeting square.MysticSquare
+	if square.KeyOf(initialState) == square.KeyOf(targetState) {
+		meeting = initialState
+	}
+
+	for meeting == nil && len(forward.frontier) > 0 && len(backward.frontier) > 0 {
+		if len(forward.frontier) <= len(backward.frontier) {
+			meeting, _, err = forward.expandLayer(ctx, budget, backward, &stats)
+		} else {
+			meeting, _, err = backward.expandLayer(ctx, budget, forward, &stats)
+		}
+		if err != nil {
+			paths = nil
+			return
+		}
+		stats.observeOpen(len(forward.frontier) + len(backward.frontier))
+		stats.observeClosed(len(forward.distance) + len(backward.distance))
+	}
+
+	if meeting == nil {
+		paths = nil
+		return
+	}
+
+	// the forward parents already lead from the meeting square back to the
+	// initial square. Reverse the backward parents onto the same map.
+	paths = forward.parents
+	for current := meeting; ; {
+		next := backward.parents[square.KeyOf(current)]
+		if next == nil {
+			break
+		}
+		paths[square.KeyOf(next)] = current
+		current = next
+	}
+	pathFound = true
+	return
+}
@@ -0,0 +1,56 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+
+	"mysticsquare/square"
+)
+
+// limits on the work a search may do. Zero means no limit.
+type SearchBudget struct {
+	MaxNodes  int
+	MaxMemory int64
+}
+
+// returned by a search that stopped because it ran out of budget
+var ErrBudgetExceeded = errors.New("search budget exceeded")
+
+// rough cost of a map entry on top of its key and value
+const MAP_ENTRY_OVERHEAD = 48
+
+// rough cost of a square.StateKey and of a packed square behind an interface
+const (
+	PACKED_KEY_BYTES    = 24
+	PACKED_SQUARE_BYTES = 32
+)
+
+// rough number of bytes one entry of an open list, closed set or parent map
+// holds for a board of the given width. Boards that fit in a packed square
+// hold a key and a packed square. Wider boards hold the state string used as
+// the key and a share of the map based square it refers to.
+func stateBytes(size int) (bytes int64) {
+	if size <= square.MAX_PACKED_SIZE {
+		bytes = PACKED_KEY_BYTES + PACKED_SQUARE_BYTES + MAP_ENTRY_OVERHEAD
+		return
+	}
+	tiles := int64(size * size)
+	stateStringBytes := tiles * 3
+	squareBytes := tiles*2*8*2 + MAP_ENTRY_OVERHEAD
+	bytes = stateStringBytes + squareBytes/2 + MAP_ENTRY_OVERHEAD
+	return
+}
+
+// check the search is still within budget. entries is the number of entries
+// currently held by the open list, closed set and parent map together.
+func (budget SearchBudget) check(stats *SearchStatistics, size, entries int) (err error) {
+	memory := int64(entries) * stateBytes(size)
+	stats.PeakMemoryEstimate = max(stats.PeakMemoryEstimate, memory)
+	switch {
+	case budget.MaxNodes > 0 && stats.NodesExpanded >= budget.MaxNodes:
+		err = fmt.Errorf("%w: expanded %v nodes, limit %v", ErrBudgetExceeded, stats.NodesExpanded, budget.MaxNodes)
+	case budget.MaxMemory > 0 && memory > budget.MaxMemory:
+		err = fmt.Errorf("%w: about %v bytes held, limit %v", ErrBudgetExceeded, memory, budget.MaxMemory)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package solver
+
+import (
+	"sort"
+
+	"mysticsquare/square"
+)
+
+// minimum number of tiles that must leave a line so that the remaining tiles
+// are in the same relative order as their goal positions. goals holds, for
+// every tile in the line that belongs to the line, its goal index in order of
+// the tile's current position. The tiles that stay are a longest increasing
+// subsequence of goals.
+func lineConflicts(goals []int) (removed int) {
+	removed = len(goals) - longestIncreasingSubsequence(goals)
+	return
+}
+
+// length of the longest strictly increasing subsequence of values. tails[k]
+// is the smallest value that ends an increasing subsequence of length k+1.
+func longestIncreasingSubsequence(values []int) (length int) {
+	tails := make([]int, 0, len(values))
+	for _, value := range values {
+		if idx := sort.SearchInts(tails, value); idx == len(tails) {
+			tails = append(tails, value)
+		} else {
+			tails[idx] = value
+		}
+	}
+	length = len(tails)
+	return
+}
+
+// implementation of the linear conflict heuristic. Two tiles in their goal
+// line but in reversed order must pass each other, which costs at least two
+// moves on top of their manhattan distance.
+func LinearConflict(current, target square.MysticSquare) (distance int) {
+	distance = linearConflictFromGoals(current, goalPositions(target))
+	return
+}
+
+// goal position of every tile of target, indexed by tile value
+func goalPositions(target square.MysticSquare) (goals []int) {
+	goals = make([]int, square.BlankTile(target.Size())+1)
+	for position, value := range target.RealState() {
+		goals[value] = position
+	}
+	return
+}
+
+// read the tiles of current by position. Packed squares are read in place
+// instead of copying their state.
+func tileReader(current square.MysticSquare) (tileAt func(position int) int) {
+	if packed, ok := current.(square.PackedSquare); ok {
+		tileAt = packed.Tile
+		return
+	}
+	state := current.RealState()
+	tileAt = func(position int) int { return state[position] }
+	return
+}
+
+// manhattan distance of current to the goal positions from goalPositions
+func manhattanFromGoals(current square.MysticSquare, goals []int) (distance int) {
+	distance = 0
+	size := current.Size()
+	blank := square.BlankTile(size)
+	tileAt := tileReader(current)
+	for currentPosition := 1; currentPosition <= size*size; currentPosition++ {
+		currentValue := tileAt(currentPosition)
+		if targetPosition := goals[currentValue]; targetPosition != currentPosition && currentValue != blank {
+			currentRow, currentColumn := (currentPosition-1)/size, (currentPosition-1)%size
+			targetRow, targetColumn := (targetPosition-1)/size, (targetPosition-1)%size
+			distance += abs(currentRow-targetRow) + abs(currentColumn-targetColumn)
+		}
+	}
+	return
+}
+
+// absolute value of an integer
+func abs(value int) (absolute int) {
+	absolute = max(value, -value)
+	return
+}
+
+// linear conflict of current to the goal positions from goalPositions
+func linearConflictFromGoals(current square.MysticSquare, targetPositions []int) (distance int) {
+	distance = manhattanFromGoals(current, targetPositions)
+
+	size := current.Size()
+	blank := square.BlankTile(size)
+	tileAt := tileReader(current)
+
+	for line := 0; line < size; line++ {
+		rowGoals := make([]int, 0, size)
+		columnGoals := make([]int, 0, size)
+		for offset := 0; offset < size; offset++ {
+			if value := tileAt(1 + offset + size*line); value != blank {
+				if targetPosition := targetPositions[value]; (targetPosition-1)/size == line {
+					rowGoals = append(rowGoals, (targetPosition-1)%size)
+				}
+			}
+			if value := tileAt(1 + line + size*offset); value != blank {
+				if targetPosition := targetPositions[value]; (targetPosition-1)%size == line {
+					columnGoals = append(columnGoals, (targetPosition-1)/size)
+				}
+			}
+		}
+		distance += 2 * (lineConflicts(rowGoals) + lineConflicts(columnGoals))
+	}
+	return
+}
@@ -0,0 +1,160 @@
+package solver
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"mysticsquare/pdb"
+	"mysticsquare/square"
+)
+
+// number of boards sampled by the optimality tests
+const SAMPLED_BOARDS = 25
+
+// a start and target the optimality tests solve
+type sampledPuzzle struct {
+	start, target square.MysticSquare
+}
+
+// random solvable 3x3 boards, half of them for the solved target and half for
+// a random target so goal positions other than the usual ones are covered
+func samplePuzzles(t *testing.T) (puzzles []sampledPuzzle) {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(3, 3))
+	solved, err := square.NewMysticSquare(square.SolvedState(3))
+	if err != nil {
+		t.Fatalf("solved board: %v", err)
+	}
+	for idx := 0; idx < SAMPLED_BOARDS; idx++ {
+		target := solved
+		if idx%2 == 1 {
+			target = square.RandomWalk(rng, solved, 40)
+		}
+		start, err := square.RandomSolvable(rng, target)
+		if err != nil {
+			t.Fatalf("random board: %v", err)
+		}
+		puzzles = append(puzzles, sampledPuzzle{start: start, target: target})
+	}
+	return
+}
+
+// optimal costs already found, by start and target
+var optimalCosts = make(map[[2]square.StateKey]int)
+
+// cost of an optimal solution, found by breadth first search
+func optimalCost(t *testing.T, puzzle sampledPuzzle) (cost int) {
+	t.Helper()
+	key := [2]square.StateKey{square.KeyOf(puzzle.start), square.KeyOf(puzzle.target)}
+	if cached, exists := optimalCosts[key]; exists {
+		cost = cached
+		return
+	}
+	result, err := Solve(context.Background(), puzzle.start, puzzle.target, Options{Algorithm: BREADTH_FIRST_SEARCH})
+	if err != nil || result.Outcome != SOLVED_OUTCOME {
+		t.Fatalf("bfs from %v: %v %v", square.FormatCompact(puzzle.start), result, err)
+	}
+	cost = result.Cost
+	optimalCosts[key] = cost
+	return
+}
+
+// check A star and IDA star with the options for each target find solutions
+// as short as breadth first search does
+func checkOptimal(t *testing.T, targetOptions func(target square.MysticSquare) Options) {
+	t.Helper()
+	for _, puzzle := range samplePuzzles(t) {
+		expected := optimalCost(t, puzzle)
+		options := targetOptions(puzzle.target)
+		for _, algorithm := range []AlgorithmSelection{A_STAR_SEARCH, IDA_STAR_SEARCH} {
+			options.Algorithm = algorithm
+			result, err := Solve(context.Background(), puzzle.start, puzzle.target, options)
+			if err != nil {
+				t.Fatalf("%v from %v to %v: %v", algorithm, square.FormatCompact(puzzle.start), square.FormatCompact(puzzle.target), err)
+			}
+			if result.Cost != expected {
+				t.Fatalf("%v with %v from %v to %v costs %v, expected %v", algorithm, options.Heuristic, square.FormatCompact(puzzle.start), square.FormatCompact(puzzle.target), result.Cost, expected)
+			}
+		}
+	}
+}
+
+func TestLinearConflictOptimal(t *testing.T) {
+	checkOptimal(t, func(square.MysticSquare) Options { return Options{Heuristic: LINEAR_CONFLICT} })
+}
+
+func TestLinearConflictAdmissible(t *testing.T) {
+	for _, puzzle := range samplePuzzles(t) {
+		h := Options{Heuristic: LINEAR_CONFLICT}.realHeuristic(puzzle.target)
+		if estimate, cost := h(puzzle.start), optimalCost(t, puzzle); estimate > cost {
+			t.Fatalf("linear conflict from %v to %v estimates %v, the optimal cost is %v", square.FormatCompact(puzzle.start), square.FormatCompact(puzzle.target), estimate, cost)
+		}
+	}
+}
+
+// every ordering of 0..n-1
+func permutations(n int) (orders [][]int) {
+	if n == 0 {
+		orders = [][]int{{}}
+		return
+	}
+	for _, shorter := range permutations(n - 1) {
+		for position := 0; position <= len(shorter); position++ {
+			order := append(append(append([]int{}, shorter[:position]...), n-1), shorter[position:]...)
+			orders = append(orders, order)
+		}
+	}
+	return
+}
+
+// fewest values to remove so the rest are increasing, trying every subset
+func fewestRemovals(goals []int) (removed int) {
+	removed = len(goals)
+	for kept := 0; kept < 1<<len(goals); kept++ {
+		last, increasing, count := -1, true, 0
+		for idx, goal := range goals {
+			if kept&(1<<idx) == 0 {
+				continue
+			}
+			increasing = increasing && goal > last
+			last = goal
+			count++
+		}
+		if increasing {
+			removed = min(removed, len(goals)-count)
+		}
+	}
+	return
+}
+
+func TestLineConflicts(t *testing.T) {
+	for n := 0; n <= 7; n++ {
+		for _, goals := range permutations(n) {
+			if got, expected := lineConflicts(goals), fewestRemovals(goals); got != expected {
+				t.Fatalf("lineConflicts(%v) = %v, expected %v", goals, got, expected)
+			}
+		}
+	}
+}
+
+func TestPatternDatabaseOptimal(t *testing.T) {
+	partitions := [][][]int{
+		{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		{{1, 2, 3}, {4, 5, 6}, {7, 8}},
+	}
+	for _, partition := range partitions {
+		databases := make(map[square.StateKey]*pdb.PatternDatabase)
+		checkOptimal(t, func(target square.MysticSquare) Options {
+			db, exists := databases[square.KeyOf(target)]
+			if !exists {
+				var err error
+				if db, err = pdb.NewPatternDatabase(target, partition); err != nil {
+					t.Fatalf("pattern database %v for %v: %v", partition, square.FormatCompact(target), err)
+				}
+				databases[square.KeyOf(target)] = db
+			}
+			return Options{Heuristic: PATTERN_DATABASE, PatternDatabase: db}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package solver
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"mysticsquare/square"
+)
+
+// iterative deepening a* search implementation. Only the current path is kept
+// in memory so the memory used is linear in the depth of the solution.
+// thresholds holds every f bound that was searched, in order. The open list
+// and closed set in stats are both the current path.
+func idaStar(ctx context.Context, budget SearchBudget, initialState, targetState square.MysticSquare, h func(square.MysticSquare) int) (paths map[square.StateKey]square.MysticSquare, pathFound bool, thresholds []int, stats SearchStatistics, err error) {
+	if h == nil {
+		panic("Invalid heuristic function")
+	}
+	started := time.Now()
+	defer func() { stats.WallTime = time.Since(started) }()
+	h = stats.countHeuristic(h)
+
+	targetKey := square.KeyOf(targetState)
+	path := []square.MysticSquare{initialState}
+	onPath := map[square.StateKey]bool{square.KeyOf(initialState): true}
+
+	// depth first search bounded by threshold. Returns the smallest f value
+	// that exceeded the threshold when the target was not found.
+	var search func(g, threshold int) (found bool, nextThreshold int)
+	search = func(g, threshold int) (found bool, nextThreshold int) {
+		if err = searchCancelled(ctx); err != nil {
+			return
+		}
+		if err = budget.check(&stats, initialState.Size(), len(path)+len(onPath)); err != nil {
+			return
+		}
+		current := path[len(path)-1]
+		f := g + h(current)
+		if f > threshold {
+			nextThreshold = f
+			return
+		}
+		if square.KeyOf(current) == targetKey {
+			found = true
+			return
+		}
+
+		nextThreshold = math.MaxInt
+		stats.NodesExpanded++
+		adjacent := adjacentSquares(current)
+		stats.NodesGenerated += len(adjacent)
+		for _, neighbor := range adjacent {
+			neighborKey := square.KeyOf(neighbor)
+			if onPath[neighborKey] {
+				stats.DuplicatesSkipped++
+				continue
+			}
+			path = append(path, neighbor)
+			onPath[neighborKey] = true
+			stats.observeOpen(len(path))
+			stats.observeClosed(len(onPath))
+			neighborFound, neighborThreshold := search(g+1, threshold)
+			if neighborFound {
+				found = true
+				return
+			}
+			if err != nil {
+				return
+			}
+			delete(onPath, neighborKey)
+			path = path[:len(path)-1]
+			nextThreshold = min(nextThreshold, neighborThreshold)
+		}
+		return
+	}
+
+	thresholds = make([]int, 0)
+	pathFound = false
+	for threshold := h(initialState); threshold != math.MaxInt; {
+		thresholds = append(thresholds, threshold)
+		found, nextThreshold := search(0, threshold)
+		if found {
+			pathFound = true
+			break
+		}
+		if err != nil {
+			break
+		}
+		threshold = nextThreshold
+	}
+
+	paths = make(map[square.StateKey]square.MysticSquare)
+	if pathFound {
+		paths[square.KeyOf(initialState)] = nil
+		for idx := 1; idx < len(path); idx++ {
+			paths[square.KeyOf(path[idx])] = path[idx-1]
+		}
+	}
+	return
+}
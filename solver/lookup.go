@@ -0,0 +1,33 @@
+package solver
+
+import (
+	"context"
+	"time"
+
+	"mysticsquare/lookup"
+	"mysticsquare/square"
+)
+
+// read an optimal path out of a lookup table. The table already holds the
+// distance of every square, so nothing is searched: each step examines the
+// neighbors of one square and moves to the one closer to the target.
+func lookupTableSearch(ctx context.Context, table *lookup.Table, initialState, targetState square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+	startTime := time.Now()
+	defer func() { stats.WallTime = time.Since(startTime) }()
+	paths = make(map[square.StateKey]square.MysticSquare)
+	if err = searchCancelled(ctx); err != nil {
+		return
+	}
+	path, solveErr := table.Solve(initialState)
+	if solveErr != nil {
+		err = solveErr
+		return
+	}
+	for idx := 1; idx < len(path); idx++ {
+		paths[square.KeyOf(path[idx])] = path[idx-1]
+		stats.NodesExpanded++
+		stats.NodesGenerated += len(square.Neighbors(path[idx-1]))
+	}
+	pathFound = square.KeyOf(path[len(path)-1]) == square.KeyOf(targetState)
+	return
+}
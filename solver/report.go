@@ -0,0 +1,80 @@
+package solver
+
+import (
+	"mysticsquare/square"
+)
+
+// version of the json report. Bumped whenever a field is removed or changes
+// meaning; new fields may be added without a bump.
+const REPORT_SCHEMA_VERSION = 1
+
+// everything known about a single solve, in the form written by run --output
+// json and returned by the serve command
+type Report struct {
+	SchemaVersion  int      `json:"schema_version"`
+	Size           int      `json:"size"`
+	Start          string   `json:"start"`
+	Target         string   `json:"target"`
+	Algorithm      string   `json:"algorithm"`
+	Heuristic      string   `json:"heuristic"`
+	Outcome        string   `json:"outcome"`
+	Solvable       bool     `json:"solvable"`
+	Reason         string   `json:"reason"`
+	PathFound      bool     `json:"path_found"`
+	SolutionLength int      `json:"solution_length"`
+	Moves          []string `json:"moves"`
+	MoveString     string   `json:"move_string"`
+	Convention     string   `json:"convention"`
+	States         []string `json:"states"`
+	Thresholds     []int    `json:"thresholds"`
+	ElapsedNanos   int64    `json:"elapsed_ns"`
+
+	Statistics *SearchStatistics `json:"statistics"`
+
+	// squares from start to target, nil unless solved
+	Path []square.MysticSquare `json:"-"`
+}
+
+// report what Solve found for start and target with the given options. Moves
+// are written in the given convention.
+func NewReport(start, target square.MysticSquare, options Options, convention square.MoveConvention, result *Result) (report *Report) {
+	options, _ = options.withDefaults()
+	report = &Report{
+		SchemaVersion: REPORT_SCHEMA_VERSION,
+		Size:          start.Size(),
+		Start:         square.FormatCompact(start),
+		Target:        square.FormatCompact(target),
+		Algorithm:     options.Algorithm.String(),
+		Heuristic:     "none",
+		Outcome:       result.Outcome,
+		Solvable:      result.Solvable,
+		Reason:        result.Reason,
+		Moves:         make([]string, 0),
+		States:        make([]string, 0),
+		Thresholds:    make([]int, 0),
+		Convention:    convention.String(),
+	}
+	if options.Algorithm.Informed() {
+		report.Heuristic = options.Heuristic.String()
+	}
+	if result.Outcome == UNSOLVABLE_OUTCOME {
+		return
+	}
+	report.ElapsedNanos = result.Elapsed.Nanoseconds()
+	statistics := result.Statistics
+	report.Statistics = &statistics
+	if result.Thresholds != nil {
+		report.Thresholds = result.Thresholds
+	}
+	if result.Path != nil {
+		report.PathFound = true
+		report.Path = result.Path
+		report.SolutionLength = result.Cost
+		report.Moves = result.Moves
+		for _, current := range result.Path {
+			report.States = append(report.States, square.FormatCompact(current))
+		}
+		report.MoveString = square.FormatMoves(result.Moves, convention)
+	}
+	return
+}
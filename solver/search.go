@@ -0,0 +1,303 @@
+package solver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"mysticsquare/datastructures"
+	"mysticsquare/square"
+)
+
+// manhattan distance of current to target. Panics on invalid squares or squares
+// of different widths.
+func ManhattanDistance(current, target square.MysticSquare) (distance int) {
+
+	if currentValid := current.ValidateState(); !currentValid {
+		panic(fmt.Sprintf("passed an invalid state to manhattan distance function: %v", current.RealState()))
+	}
+
+	if targetValid := target.ValidateState(); !targetValid {
+		panic(fmt.Sprintf("passed an invalid state to manhattan distance function: %v", target.RealState()))
+	}
+
+	if sameWidth := current.Size() == target.Size(); !sameWidth {
+		panic(fmt.Sprintf("Board width missmatch: %v, %v", current.Size(), target.Size()))
+	}
+
+	distance = manhattanFromGoals(current, goalPositions(target))
+	return
+}
+
+// squares reachable from current with a single move of the empty space
+func adjacentSquares(current square.MysticSquare) (adjacent []square.MysticSquare) {
+	adjacent = make([]square.MysticSquare, 0, 4)
+	if packed, ok := current.(square.PackedSquare); ok {
+		for _, direction := range []string{square.LEFT, square.RIGHT, square.UP, square.DOWN} {
+			if next, moved := packed.Step(direction); moved {
+				adjacent = append(adjacent, next)
+			}
+		}
+		return
+	}
+	for _, move := range []func() map[int]int{current.MoveLeft, current.MoveRight, current.MoveUp, current.MoveDown} {
+		if state := move(); state != nil {
+			if newSquare, err := square.NewMysticSquare(state); err == nil {
+				adjacent = append(adjacent, newSquare)
+			}
+		}
+	}
+	return
+}
+
+// priority of a square in the a* open list
+type aStarKey struct {
+	f, h int
+}
+
+// lower f leaves first. Among equal f the square closer to the target by the
+// heuristic leaves first, which tends to reach the target sooner.
+func (key aStarKey) compare(other aStarKey) int {
+	if key.f != other.f {
+		return cmp.Compare(key.f, other.f)
+	}
+	return cmp.Compare(key.h, other.h)
+}
+
+// bucket of the key in the order of compare. h never exceeds f, so the keys
+// with f = n take the n+1 buckets after the n*(n+1)/2 taken by lower f.
+func (key aStarKey) bucket() int {
+	return key.f*(key.f+1)/2 + key.h
+}
+
+// create an open list of the given kind. A heap orders squares by compare and
+// a bucket queue by bucket, which must give the same order. Both then take
+// squares with equal keys in the order of ties, so either list expands the
+// same squares.
+func newOpenList[K any](kind string, compare func(a, b K) int, bucket func(K) int, ties datastructures.TieOrder) (q datastructures.KeyedQueue[square.MysticSquare, K]) {
+	if kind == BUCKET_OPEN_LIST {
+		q = datastructures.NewBucketQueue[square.MysticSquare](bucket, ties)
+		return
+	}
+	if ties == datastructures.LAST_IN_FIRST_OUT {
+		q = datastructures.NewHeap(datastructures.LastInFirstOut[square.MysticSquare](compare))
+		return
+	}
+	q = datastructures.NewHeap(datastructures.FirstInFirstOut[square.MysticSquare](compare))
+	return
+}
+
+// a* search implementation
+func aStar(ctx context.Context, budget SearchBudget, openList string, initialState, targetState square.MysticSquare, h func(square.MysticSquare) int) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+	if h == nil {
+		panic("Invalid heuristic function")
+	}
+	started := time.Now()
+	defer func() { stats.WallTime = time.Since(started) }()
+	h = stats.countHeuristic(h)
+
+	// lowest f, then lowest h, then last in first out, which favours the
+	// squares reached most recently
+	q := newOpenList(openList, aStarKey.compare, aStarKey.bucket, datastructures.LAST_IN_FIRST_OUT)
+
+	paths = make(map[square.StateKey]square.MysticSquare)
+	paths[square.KeyOf(initialState)] = nil
+
+	distance := make(map[square.StateKey]int)
+	distance[square.KeyOf(initialState)] = 0
+
+	g := func(state square.MysticSquare) (g int) {
+		g = distance[square.KeyOf(state)]
+		return
+	}
+
+	priority := func(state square.MysticSquare) (key aStarKey) {
+		detectOverflow := func(a, b int) (err error) {
+			ErrOverflow := errors.New("integer overflow detected")
+			if b > 0 {
+				if a > math.MaxInt-b {
+					err = ErrOverflow
+					return
+				}
+			}
+			err = nil
+			return
+		}
+		gCurrent := g(state)
+		key.h = h(state)
+		if err := detectOverflow(gCurrent, key.h); err == nil {
+			key.f = gCurrent + key.h
+		} else {
+			key.f = math.MaxInt
+		}
+
+		return
+	}
+
+	itemsMap := make(map[square.StateKey]*datastructures.Item[square.MysticSquare, aStarKey])
+	itemsMap[square.KeyOf(initialState)] = q.Push(initialState, priority(initialState))
+	visited := make(map[square.StateKey]bool)
+
+	pathFound = false
+	targetKey := square.KeyOf(targetState)
+
+	for currentItem, itemExists := q.Process(); itemExists; currentItem, itemExists = q.Process() {
+		if err = searchCancelled(ctx); err != nil {
+			break
+		}
+		if err = budget.check(&stats, initialState.Size(), q.Len()+len(visited)+len(paths)); err != nil {
+			break
+		}
+		current := currentItem.Value
+		currentKey := square.KeyOf(current)
+		if currentKey == targetKey {
+			pathFound = true
+			break
+		}
+		stats.NodesExpanded++
+		adjacent := adjacentSquares(current)
+
+		stats.NodesGenerated += len(adjacent)
+		for _, neighbor := range adjacent {
+			neighborKey := square.KeyOf(neighbor)
+			tentativeDistance := distance[currentKey] + 1
+
+			if _, distanceForNeighborExists := distance[neighborKey]; !distanceForNeighborExists {
+				paths[neighborKey] = current
+				distance[neighborKey] = tentativeDistance
+				itemsMap[neighborKey] = q.Push(neighbor, priority(neighbor))
+				continue
+			}
+			stats.DuplicatesSkipped++
+
+			neighborDistance := distance[neighborKey]
+			if _, neighborVisited := visited[neighborKey]; tentativeDistance < neighborDistance && !neighborVisited {
+				paths[neighborKey] = current
+				distance[neighborKey] = tentativeDistance
+				item := itemsMap[neighborKey]
+				q.Update(item, priority(neighbor))
+			}
+		}
+		visited[currentKey] = true
+		stats.observeOpen(q.Len())
+		stats.observeClosed(len(visited))
+	}
+	return
+}
+
+// dijkstras algorithm implementation
+func dijkstrasAlgorithm(ctx context.Context, budget SearchBudget, openList string, initialState, targetState square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+	started := time.Now()
+	defer func() { stats.WallTime = time.Since(started) }()
+
+	// lowest distance, then first in first out
+	q := newOpenList(openList, cmp.Compare[int], func(distance int) int { return distance }, datastructures.FIRST_IN_FIRST_OUT)
+
+	paths = make(map[square.StateKey]square.MysticSquare)
+	paths[square.KeyOf(initialState)] = nil
+
+	distance := make(map[square.StateKey]int)
+	distance[square.KeyOf(initialState)] = 0
+
+	itemsMap := make(map[square.StateKey]*datastructures.Item[square.MysticSquare, int])
+	itemsMap[square.KeyOf(initialState)] = q.Push(initialState, distance[square.KeyOf(initialState)])
+
+	pathFound = false
+	targetKey := square.KeyOf(targetState)
+
+	for currentItem, itemExists := q.Process(); itemExists; currentItem, itemExists = q.Process() {
+		if err = searchCancelled(ctx); err != nil {
+			break
+		}
+		if err = budget.check(&stats, initialState.Size(), q.Len()+stats.NodesExpanded+len(paths)); err != nil {
+			break
+		}
+
+		current := currentItem.Value
+		currentKey := square.KeyOf(current)
+
+		if currentKey == targetKey {
+			pathFound = true
+			break
+		}
+		stats.NodesExpanded++
+
+		adjacent := adjacentSquares(current)
+
+		stats.NodesGenerated += len(adjacent)
+		for _, value := range adjacent {
+			valueKey := square.KeyOf(value)
+			altDistance := distance[currentKey] + 1
+			if _, distanceExists := distance[valueKey]; !distanceExists {
+				paths[valueKey] = current
+				distance[valueKey] = altDistance
+				itemsMap[valueKey] = q.Push(value, altDistance)
+				continue
+			}
+			stats.DuplicatesSkipped++
+
+			currentDistanceForValue := distance[valueKey]
+			if altDistance < currentDistanceForValue {
+				paths[valueKey] = current
+				distance[valueKey] = altDistance
+				item := itemsMap[valueKey]
+				q.Update(item, altDistance)
+			}
+		}
+		stats.observeOpen(q.Len())
+		stats.observeClosed(stats.NodesExpanded)
+	}
+
+	return
+}
+
+// bfs implementation
+func bfs(ctx context.Context, budget SearchBudget, initialState square.MysticSquare, targetState square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+	started := time.Now()
+	defer func() { stats.WallTime = time.Since(started) }()
+
+	q := datastructures.NewMysticSquareQueue()
+	visited := make(map[square.StateKey]bool)
+	paths = make(map[square.StateKey]square.MysticSquare)
+	pathFound = false
+	q.Push(initialState)
+	paths[square.KeyOf(initialState)] = nil
+	visited[square.KeyOf(initialState)] = true
+
+	for current, hasItem := q.Process(); hasItem; current, hasItem = q.Process() {
+		if err = searchCancelled(ctx); err != nil {
+			break
+		}
+		if err = budget.check(&stats, initialState.Size(), q.Len()+len(visited)+len(paths)); err != nil {
+			break
+		}
+
+		if pathFound = (square.KeyOf(current) == square.KeyOf(targetState)); pathFound {
+			break
+		}
+		stats.NodesExpanded++
+
+		adjacent := adjacentSquares(current)
+
+		stats.NodesGenerated += len(adjacent)
+		for _, newSquare := range adjacent {
+			if newKey := square.KeyOf(newSquare); !visited[newKey] {
+				q.Push(newSquare)
+				paths[newKey] = current
+				visited[newKey] = true
+			} else {
+				stats.DuplicatesSkipped++
+			}
+		}
+		stats.observeOpen(q.Len())
+		stats.observeClosed(len(visited))
+	}
+	if !pathFound {
+		paths = nil
+	}
+
+	return
+}
@@ -0,0 +1,320 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"mysticsquare/lookup"
+	"mysticsquare/pdb"
+	"mysticsquare/square"
+)
+
+type AlgorithmSelection int
+type HeuristicSelection int
+
+// algorithm constants
+const (
+	A_STAR_SEARCH        AlgorithmSelection = 1
+	DIJKSTRAS_ALGORITHM  AlgorithmSelection = 2
+	BREADTH_FIRST_SEARCH AlgorithmSelection = 3
+	IDA_STAR_SEARCH      AlgorithmSelection = 4
+	BIDIRECTIONAL_BFS    AlgorithmSelection = 5
+	LOOKUP_TABLE         AlgorithmSelection = 6
+)
+
+// heuristic constants
+const (
+	MANHATTAN_DISTANCE HeuristicSelection = 1
+	LINEAR_CONFLICT    HeuristicSelection = 2
+	PATTERN_DATABASE   HeuristicSelection = 3
+)
+
+// name of the algorithm
+func (algorithm AlgorithmSelection) String() (name string) {
+	switch algorithm {
+	case A_STAR_SEARCH:
+		name = "astar"
+	case DIJKSTRAS_ALGORITHM:
+		name = "dijkstra"
+	case BREADTH_FIRST_SEARCH:
+		name = "bfs"
+	case IDA_STAR_SEARCH:
+		name = "idastar"
+	case BIDIRECTIONAL_BFS:
+		name = "bidirectional-bfs"
+	case LOOKUP_TABLE:
+		name = "table"
+	default:
+		name = "unknown"
+	}
+	return
+}
+
+// check if the algorithm uses a heuristic
+func (algorithm AlgorithmSelection) Informed() (informed bool) {
+	informed = algorithm == A_STAR_SEARCH || algorithm == IDA_STAR_SEARCH
+	return
+}
+
+// check if the algorithm keeps its frontier in a priority queue
+func (algorithm AlgorithmSelection) UsesOpenList() (usesOpenList bool) {
+	usesOpenList = algorithm == A_STAR_SEARCH || algorithm == DIJKSTRAS_ALGORITHM
+	return
+}
+
+// open list constants
+const (
+	HEAP_OPEN_LIST   = "heap"
+	BUCKET_OPEN_LIST = "bucket"
+)
+
+// name of the heuristic
+func (heuristic HeuristicSelection) String() (name string) {
+	switch heuristic {
+	case MANHATTAN_DISTANCE:
+		name = "manhattan"
+	case LINEAR_CONFLICT:
+		name = "linear-conflict"
+	case PATTERN_DATABASE:
+		name = "pattern-database"
+	default:
+		name = "unknown"
+	}
+	return
+}
+
+// outcome constants
+const (
+	SOLVED_OUTCOME          = "solved"
+	UNSOLVABLE_OUTCOME      = "unsolvable"
+	NO_PATH_OUTCOME         = "no_path"
+	CANCELLED_OUTCOME       = "cancelled"
+	BUDGET_EXCEEDED_OUTCOME = "budget_exceeded"
+)
+
+// returned by Solve when the pattern database or lookup table in the options
+// was built for another target
+var ErrWrongTarget = errors.New("built for a different target")
+
+// how Solve searches. The zero value solves with IDA star and the linear
+// conflict heuristic without any limit.
+type Options struct {
+	// A_STAR_SEARCH and the other algorithm constants. 0 means IDA_STAR_SEARCH.
+	Algorithm AlgorithmSelection
+	// heuristic of A star and IDA star. 0 means LINEAR_CONFLICT.
+	Heuristic HeuristicSelection
+	// HEAP_OPEN_LIST or BUCKET_OPEN_LIST for A star and Dijkstras. Empty
+	// means HEAP_OPEN_LIST.
+	OpenList string
+	// needed by the PATTERN_DATABASE heuristic
+	PatternDatabase *pdb.PatternDatabase
+	// needed by the LOOKUP_TABLE algorithm
+	Table *lookup.Table
+	// stop the search after this long. 0 means no limit.
+	Timeout time.Duration
+	Budget  SearchBudget
+}
+
+// what Solve found
+type Result struct {
+	// SOLVED_OUTCOME or one of the other outcome constants
+	Outcome string
+	// why the board is unsolvable or the search stopped, empty otherwise
+	Reason   string
+	Solvable bool
+	// squares from start to target, both included. nil unless solved.
+	Path []square.MysticSquare
+	// directions the empty space moves, square.UP and so on. Format them
+	// with square.FormatMoves.
+	Moves []string
+	// number of moves of the solution. -1 unless solved.
+	Cost int
+	// every f bound IDA star searched, in order
+	Thresholds []int
+	Statistics SearchStatistics
+	Elapsed    time.Duration
+}
+
+// a search from is to ts. When ctx is done the search stops and err wraps
+// ErrSearchCancelled; when it runs out of budget err wraps
+// ErrBudgetExceeded. stats then hold the work done until that point.
+type SearchAlgorithm func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error)
+
+// returned by a search that was stopped before it finished
+var ErrSearchCancelled = errors.New("search cancelled")
+
+// check whether the search must stop
+func searchCancelled(ctx context.Context) (err error) {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = fmt.Errorf("%w: %w", ErrSearchCancelled, ctxErr)
+	}
+	return
+}
+
+// extra information an algorithm reports alongside its path
+type searchDetails struct {
+	thresholds []int
+}
+
+// heuristic selected by the options for the given target
+func (options Options) realHeuristic(ts square.MysticSquare) (h func(square.MysticSquare) int) {
+	switch options.Heuristic {
+	case PATTERN_DATABASE:
+		h = options.PatternDatabase.Heuristic
+	case LINEAR_CONFLICT:
+		goals := goalPositions(ts)
+		h = func(current square.MysticSquare) int { return linearConflictFromGoals(current, goals) }
+	default:
+		goals := goalPositions(ts)
+		h = func(current square.MysticSquare) int { return manhattanFromGoals(current, goals) }
+	}
+	return
+}
+
+// algorithm selected by the options. Anything the algorithm reports besides
+// the path is stored in details.
+func (options Options) realAlgorithm(details *searchDetails) (algorithm SearchAlgorithm) {
+	switch options.Algorithm {
+	case A_STAR_SEARCH:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, stats, err = aStar(ctx, options.Budget, options.OpenList, is, ts, options.realHeuristic(ts))
+			return
+		}
+	case DIJKSTRAS_ALGORITHM:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, stats, err = dijkstrasAlgorithm(ctx, options.Budget, options.OpenList, is, ts)
+			return
+		}
+	case BREADTH_FIRST_SEARCH:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, stats, err = bfs(ctx, options.Budget, is, ts)
+			return
+		}
+	case BIDIRECTIONAL_BFS:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, stats, err = bidirectionalBfs(ctx, options.Budget, is, ts)
+			return
+		}
+	case IDA_STAR_SEARCH:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, details.thresholds, stats, err = idaStar(ctx, options.Budget, is, ts, options.realHeuristic(ts))
+			return
+		}
+	case LOOKUP_TABLE:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths, pathFound, stats, err = lookupTableSearch(ctx, options.Table, is, ts)
+			return
+		}
+	default:
+		algorithm = func(ctx context.Context, is, ts square.MysticSquare) (paths map[square.StateKey]square.MysticSquare, pathFound bool, stats SearchStatistics, err error) {
+			paths = make(map[square.StateKey]square.MysticSquare)
+			pathFound = false
+			return
+		}
+	}
+	return
+}
+
+// fill in the defaults of the zero value and check everything the selected
+// algorithm and heuristic need is there
+func (options Options) withDefaults() (withDefaults Options, err error) {
+	withDefaults = options
+	if withDefaults.Algorithm == 0 {
+		withDefaults.Algorithm = IDA_STAR_SEARCH
+	}
+	if withDefaults.Heuristic == 0 {
+		withDefaults.Heuristic = LINEAR_CONFLICT
+	}
+	if withDefaults.OpenList == "" {
+		withDefaults.OpenList = HEAP_OPEN_LIST
+	}
+	switch {
+	case withDefaults.Algorithm.String() == "unknown":
+		err = fmt.Errorf("unknown algorithm %v", int(withDefaults.Algorithm))
+	case withDefaults.Heuristic.String() == "unknown":
+		err = fmt.Errorf("unknown heuristic %v", int(withDefaults.Heuristic))
+	case withDefaults.OpenList != HEAP_OPEN_LIST && withDefaults.OpenList != BUCKET_OPEN_LIST:
+		err = fmt.Errorf("unknown open list %q", withDefaults.OpenList)
+	case withDefaults.Algorithm.Informed() && withDefaults.Heuristic == PATTERN_DATABASE && withDefaults.PatternDatabase == nil:
+		err = fmt.Errorf("the pattern database heuristic needs a pattern database")
+	case withDefaults.Algorithm == LOOKUP_TABLE && withDefaults.Table == nil:
+		err = fmt.Errorf("the lookup table algorithm needs a lookup table")
+	}
+	return
+}
+
+// find a path from start to target. Boards that can not reach each other are
+// reported with UNSOLVABLE_OUTCOME, and searches stopped by ctx, the timeout
+// or the budget with CANCELLED_OUTCOME or BUDGET_EXCEEDED_OUTCOME, each with
+// the statistics gathered so far. err is only set for invalid arguments.
+func Solve(ctx context.Context, start, target square.MysticSquare, options Options) (result *Result, err error) {
+	if options, err = options.withDefaults(); err != nil {
+		return
+	}
+	if start == nil || target == nil || !start.ValidateState() || !target.ValidateState() {
+		err = fmt.Errorf("invalid square")
+		return
+	}
+	if options.Algorithm.Informed() && options.Heuristic == PATTERN_DATABASE && !options.PatternDatabase.Matches(target) {
+		err = fmt.Errorf("pattern database %w", ErrWrongTarget)
+		return
+	}
+	if options.Algorithm == LOOKUP_TABLE && !options.Table.Matches(target) {
+		err = fmt.Errorf("lookup table %w", ErrWrongTarget)
+		return
+	}
+
+	result = &Result{Cost: -1}
+	if result.Solvable, result.Reason = square.Solvable(start, target); !result.Solvable {
+		result.Outcome = UNSOLVABLE_OUTCOME
+		return
+	}
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	// searches run on packed squares when the board fits in one
+	start, target = square.Compact(start), square.Compact(target)
+
+	details := &searchDetails{}
+	algorithm := options.realAlgorithm(details)
+	started := time.Now()
+	paths, pathFound, stats, searchErr := algorithm(ctx, start, target)
+	result.Elapsed = time.Since(started)
+	result.Statistics = stats
+	result.Thresholds = details.thresholds
+	switch {
+	case errors.Is(searchErr, ErrSearchCancelled):
+		result.Outcome = CANCELLED_OUTCOME
+		result.Reason = searchErr.Error()
+	case errors.Is(searchErr, ErrBudgetExceeded):
+		result.Outcome = BUDGET_EXCEEDED_OUTCOME
+		result.Reason = searchErr.Error()
+	case searchErr != nil:
+		result = nil
+		err = searchErr
+	case !pathFound:
+		result.Outcome = NO_PATH_OUTCOME
+	default:
+		result.Outcome = SOLVED_OUTCOME
+		path := make([]square.MysticSquare, 0)
+		for current := target; paths[square.KeyOf(current)] != nil; current = paths[square.KeyOf(current)] {
+			path = append(path, current)
+		}
+		path = append(path, start)
+		slices.Reverse(path)
+		result.Path = path
+		result.Cost = len(path) - 1
+		result.Moves = make([]string, 0, result.Cost)
+		for idx := 1; idx < len(path); idx++ {
+			result.Moves = append(result.Moves, square.MoveBetween(path[idx-1], path[idx]))
+		}
+	}
+	return
+}
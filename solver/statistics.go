@@ -0,0 +1,55 @@
+package solver
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"mysticsquare/square"
+)
+
+// how much work a search did
+type SearchStatistics struct {
+	NodesExpanded        int           `json:"nodes_expanded"`
+	NodesGenerated       int           `json:"nodes_generated"`
+	DuplicatesSkipped    int           `json:"duplicates_skipped"`
+	PeakOpen             int           `json:"peak_open"`
+	PeakClosed           int           `json:"peak_closed"`
+	HeuristicEvaluations int           `json:"heuristic_evaluations"`
+	PeakMemoryEstimate   int64         `json:"peak_memory_estimate_bytes"`
+	WallTime             time.Duration `json:"wall_time_ns"`
+}
+
+// record the current size of the open list
+func (stats *SearchStatistics) observeOpen(size int) {
+	stats.PeakOpen = max(stats.PeakOpen, size)
+}
+
+// record the current size of the closed set
+func (stats *SearchStatistics) observeClosed(size int) {
+	stats.PeakClosed = max(stats.PeakClosed, size)
+}
+
+// wrap a heuristic so every evaluation is counted
+func (stats *SearchStatistics) countHeuristic(h func(square.MysticSquare) int) (counted func(square.MysticSquare) int) {
+	counted = func(current square.MysticSquare) int {
+		stats.HeuristicEvaluations++
+		return h(current)
+	}
+	return
+}
+
+// write the statistics in the human readable format
+func (stats SearchStatistics) Write(w io.Writer) (err error) {
+	_, err = fmt.Fprintf(w, `STATISTICS
+nodes expanded:        %v
+nodes generated:       %v
+duplicates skipped:    %v
+peak open list:        %v
+peak closed set:       %v
+heuristic evaluations: %v
+peak memory estimate:  %v bytes
+wall time:             %v
+`, stats.NodesExpanded, stats.NodesGenerated, stats.DuplicatesSkipped, stats.PeakOpen, stats.PeakClosed, stats.HeuristicEvaluations, stats.PeakMemoryEstimate, stats.WallTime)
+	return
+}
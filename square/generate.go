@@ -0,0 +1,214 @@
+package square
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+// generation modes of Generate
+const (
+	UNIFORM_MODE  = "uniform"
+	WALK_MODE     = "walk"
+	DISTANCE_MODE = "distance"
+)
+
+// bounds on the work of a single Generate call. Zero fields mean no limit.
+type GenerateLimits struct {
+	// widest target board
+	MaxSize int
+	// most boards per call
+	MaxCount int
+	// most random moves of walk mode, over all boards of a call
+	MaxWalkMoves int
+	// widest target board of distance mode, which visits every board within
+	// the distance
+	MaxDistanceSize int
+}
+
+// error returned by Generate for arguments that are invalid or beyond its
+// GenerateLimits
+type GenerateArgsError struct {
+	Reason string
+}
+
+func (err *GenerateArgsError) Error() string {
+	return err.Reason
+}
+
+// a GenerateArgsError with the formatted reason
+func generateArgsError(format string, a ...any) (err error) {
+	err = &GenerateArgsError{Reason: fmt.Sprintf(format, a...)}
+	return
+}
+
+// check a board width is within the limits
+func (limits GenerateLimits) CheckSize(size int) (err error) {
+	switch {
+	case size < MIN_SIZE:
+		err = generateArgsError("size must be at least %v", MIN_SIZE)
+	case limits.MaxSize > 0 && size > limits.MaxSize:
+		err = generateArgsError("size must be between %v and %v", MIN_SIZE, limits.MaxSize)
+	}
+	return
+}
+
+// check the arguments of a Generate call are valid and within the limits
+func (limits GenerateLimits) check(target MysticSquare, mode string, moves, count int) (err error) {
+	if target == nil || !target.ValidateState() {
+		err = generateArgsError("invalid target square")
+		return
+	}
+	if err = limits.CheckSize(target.Size()); err != nil {
+		return
+	}
+	switch {
+	case mode != UNIFORM_MODE && mode != WALK_MODE && mode != DISTANCE_MODE:
+		err = generateArgsError("unknown mode %q, expected %v, %v or %v", mode, UNIFORM_MODE, WALK_MODE, DISTANCE_MODE)
+	case count < 1:
+		err = generateArgsError("count must be at least 1")
+	case limits.MaxCount > 0 && count > limits.MaxCount:
+		err = generateArgsError("count must be between 1 and %v", limits.MaxCount)
+	case moves < 0:
+		err = generateArgsError("moves can not be negative")
+	case mode == WALK_MODE && limits.MaxWalkMoves > 0 && moves > limits.MaxWalkMoves/count:
+		err = generateArgsError("%v mode makes at most %v moves over all boards, %v boards of %v moves is too many", WALK_MODE, limits.MaxWalkMoves, count, moves)
+	case mode == DISTANCE_MODE && limits.MaxDistanceSize > 0 && target.Size() > limits.MaxDistanceSize:
+		err = generateArgsError("%v mode is limited to %vx%v boards", DISTANCE_MODE, limits.MaxDistanceSize, limits.MaxDistanceSize)
+	}
+	return
+}
+
+// count random squares that can be solved into target. UNIFORM_MODE picks
+// among every such square, WALK_MODE scrambles target with moves random moves
+// and DISTANCE_MODE picks among the squares exactly moves moves from target.
+// Fails with a *GenerateArgsError when the arguments are beyond the limits,
+// and with the error of ctx once it is done.
+func Generate(ctx context.Context, rng *rand.Rand, target MysticSquare, mode string, moves, count int, limits GenerateLimits) (boards []MysticSquare, err error) {
+	if err = limits.check(target, mode, moves, count); err != nil {
+		return
+	}
+	if mode == DISTANCE_MODE {
+		boards, err = RandomAtDistance(ctx, rng, target, moves, count)
+		return
+	}
+
+	boards = make([]MysticSquare, 0, count)
+	for len(boards) < count {
+		if err = ctx.Err(); err != nil {
+			boards = nil
+			return
+		}
+		if mode == WALK_MODE {
+			boards = append(boards, RandomWalk(rng, target, moves))
+			continue
+		}
+		board, boardErr := RandomSolvable(rng, target)
+		if boardErr != nil {
+			boards = nil
+			err = boardErr
+			return
+		}
+		boards = append(boards, board)
+	}
+	return
+}
+
+// squares reachable from current with a single move of the empty space
+func Neighbors(current MysticSquare) (neighbors []MysticSquare) {
+	neighbors = make([]MysticSquare, 0, 4)
+	for _, direction := range []string{UP, DOWN, LEFT, RIGHT} {
+		if next := Move(current, direction); next != nil {
+			neighbors = append(neighbors, next)
+		}
+	}
+	return
+}
+
+// pick a square uniformly among all squares from which target can be
+// reached. Exchanging two tiles flips the parity, so half of the permutations
+// are solvable and a fixed exchange maps the other half onto them one to one.
+func RandomSolvable(rng *rand.Rand, target MysticSquare) (random MysticSquare, err error) {
+	size := target.Size()
+	tiles := size * size
+	permutation := rng.Perm(tiles)
+	state := make(map[int]int)
+	for position, value := range permutation {
+		state[position+1] = value + 1
+	}
+	if random, err = NewMysticSquare(state); err != nil {
+		return
+	}
+	if solvable, _ := Solvable(random, target); !solvable {
+		first, second := 1, 2
+		if state[first] == BlankTile(size) {
+			first = 3
+		} else if state[second] == BlankTile(size) {
+			second = 3
+		}
+		state[first], state[second] = state[second], state[first]
+		random, err = NewMysticSquare(state)
+	}
+	return
+}
+
+// scramble target with length random moves of the empty space. A move never
+// undoes the move before it.
+func RandomWalk(rng *rand.Rand, target MysticSquare, length int) (random MysticSquare) {
+	random = Compact(target)
+	var previous MysticSquare
+	for step := 0; step < length; step++ {
+		candidates := make([]MysticSquare, 0, 4)
+		for _, neighbor := range Neighbors(random) {
+			if previous == nil || KeyOf(neighbor) != KeyOf(previous) {
+				candidates = append(candidates, neighbor)
+			}
+		}
+		previous, random = random, candidates[rng.IntN(len(candidates))]
+	}
+	return
+}
+
+// every square whose shortest solution to target is exactly distance moves.
+// Found with a breadth-first search from target, so the memory used grows
+// with the number of squares within distance. Stops with the error of ctx
+// once it is done.
+func SquaresAtDistance(ctx context.Context, target MysticSquare, distance int) (squares []MysticSquare, err error) {
+	target = Compact(target)
+	visited := map[StateKey]bool{KeyOf(target): true}
+	squares = []MysticSquare{target}
+	for layer := 0; layer < distance && len(squares) > 0; layer++ {
+		next := make([]MysticSquare, 0)
+		for _, current := range squares {
+			if err = ctx.Err(); err != nil {
+				squares = nil
+				return
+			}
+			for _, neighbor := range Neighbors(current) {
+				if key := KeyOf(neighbor); !visited[key] {
+					visited[key] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		squares = next
+	}
+	return
+}
+
+// pick count squares uniformly among those exactly distance moves from target
+func RandomAtDistance(ctx context.Context, rng *rand.Rand, target MysticSquare, distance, count int) (random []MysticSquare, err error) {
+	candidates, err := SquaresAtDistance(ctx, target, distance)
+	if err != nil {
+		return
+	}
+	if len(candidates) == 0 {
+		err = fmt.Errorf("no square is %v moves from the target", distance)
+		return
+	}
+	random = make([]MysticSquare, 0, count)
+	for idx := 0; idx < count; idx++ {
+		random = append(random, candidates[rng.IntN(len(candidates))])
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package square
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// what a letter in the move notation describes
+type MoveConvention int
+
+// move convention constants
+const (
+	// letters give the direction the empty space moves
+	BLANK_MOVES MoveConvention = 1
+	// letters give the direction the tile next to the empty space slides
+	TILE_MOVES MoveConvention = 2
+)
+
+// letter for each direction the empty space moves
+var blankLetters = map[string]byte{UP: 'U', DOWN: 'D', LEFT: 'L', RIGHT: 'R'}
+
+// direction of the empty space opposite to each direction
+var opposite = map[string]string{UP: DOWN, DOWN: UP, LEFT: RIGHT, RIGHT: LEFT}
+
+// parse a convention name, blank or tile
+func ParseMoveConvention(name string) (convention MoveConvention, err error) {
+	switch strings.ToLower(name) {
+	case "blank":
+		convention = BLANK_MOVES
+	case "tile":
+		convention = TILE_MOVES
+	default:
+		err = fmt.Errorf("unknown move convention %q, expected blank or tile", name)
+	}
+	return
+}
+
+// name of the convention
+func (convention MoveConvention) String() (name string) {
+	switch convention {
+	case BLANK_MOVES:
+		name = "blank"
+	case TILE_MOVES:
+		name = "tile"
+	default:
+		name = "unknown"
+	}
+	return
+}
+
+// direction the empty space moves for a move in the given direction written
+// in this convention
+func (convention MoveConvention) BlankDirection(direction string) (blankDirection string) {
+	blankDirection = direction
+	if convention == TILE_MOVES {
+		blankDirection = opposite[direction]
+	}
+	return
+}
+
+// write directions of the empty space as a move string such as RDLURR
+func FormatMoves(directions []string, convention MoveConvention) (moves string) {
+	var builder strings.Builder
+	for _, direction := range directions {
+		if convention == TILE_MOVES {
+			direction = opposite[direction]
+		}
+		if letter, letterExists := blankLetters[direction]; letterExists {
+			builder.WriteByte(letter)
+		}
+	}
+	moves = builder.String()
+	return
+}
+
+// read a move string written by FormatMoves back into directions of the empty
+// space. Case, whitespace and commas are ignored.
+func ParseMoves(moves string, convention MoveConvention) (directions []string, err error) {
+	directions = make([]string, 0, len(moves))
+	for idx, letter := range moves {
+		if unicode.IsSpace(letter) || letter == ',' {
+			continue
+		}
+		direction := ""
+		for candidate, candidateLetter := range blankLetters {
+			if unicode.ToUpper(letter) == rune(candidateLetter) {
+				direction = candidate
+			}
+		}
+		if direction == "" {
+			directions = nil
+			err = fmt.Errorf("unknown move %q at offset %v", letter, idx)
+			return
+		}
+		if convention == TILE_MOVES {
+			direction = opposite[direction]
+		}
+		directions = append(directions, direction)
+	}
+	return
+}
+
+// apply a single move of the empty space. Returns nil when the move would
+// leave the board.
+func Move(current MysticSquare, direction string) (next MysticSquare) {
+	if packed, ok := current.(PackedSquare); ok {
+		if packedNext, moved := packed.Step(direction); moved {
+			next = packedNext
+		}
+		return
+	}
+	var state map[int]int
+	switch direction {
+	case UP:
+		state = current.MoveUp()
+	case DOWN:
+		state = current.MoveDown()
+	case LEFT:
+		state = current.MoveLeft()
+	case RIGHT:
+		state = current.MoveRight()
+	}
+	if state != nil {
+		if newSquare, err := NewMysticSquare(state); err == nil {
+			next = newSquare
+		}
+	}
+	return
+}
+
+// error returned by Replay for a move that leaves the board
+type IllegalMoveError struct {
+	// position of the move in the sequence, starting at 0
+	Index     int
+	Direction string
+}
+
+func (err *IllegalMoveError) Error() string {
+	return fmt.Sprintf("move %v (%v) is illegal: the empty space can not move %v", err.Index+1, err.Direction, err.Direction)
+}
+
+// apply the directions to start one after another. path holds start followed
+// by every square reached. On an illegal move path holds the squares reached
+// before it and err is an *IllegalMoveError.
+func Replay(start MysticSquare, directions []string) (path []MysticSquare, err error) {
+	path = []MysticSquare{start}
+	current := start
+	for idx, direction := range directions {
+		next := Move(current, direction)
+		if next == nil {
+			err = &IllegalMoveError{Index: idx, Direction: direction}
+			return
+		}
+		path = append(path, next)
+		current = next
+	}
+	return
+}
@@ -0,0 +1,227 @@
+package square
+
+import (
+	"fmt"
+)
+
+// widest board a PackedSquare can hold
+const MAX_PACKED_SIZE = 4
+
+// bits used by each cell of a PackedSquare
+const PACKED_CELL_BITS = 4
+
+// mask selecting a single cell of a PackedSquare
+const packedCellMask = 1<<PACKED_CELL_BITS - 1
+
+// a mystic square of up to MAX_PACKED_SIZE x MAX_PACKED_SIZE held in a single
+// integer. Cell i, counted from 0 row by row, is stored in bits 4i to 4i+3 as
+// the tile value minus one, so the blank is stored as N*N-1. Moving the blank
+// only swaps two cells and never allocates. The MysticSquare methods convert
+// to and from state maps for callers that still need them.
+type PackedSquare struct {
+	cells uint64
+	size  uint8
+	blank uint8
+}
+
+// identifies the arrangement of the tiles of a square. Boards that fit in a
+// PackedSquare are identified by their packed cells, wider boards by their
+// state string. Keys of boards with different widths must not be mixed.
+type StateKey struct {
+	packed uint64
+	wide   string
+}
+
+// create a packed square from a state map
+func NewPackedSquare(state map[int]int) (packed PackedSquare, err error) {
+	size, sizeErr := SizeForState(state)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	if size > MAX_PACKED_SIZE {
+		err = fmt.Errorf("a %vx%v board does not fit in a packed square", size, size)
+		return
+	}
+	if !(MysticSquareN{state: state, size: size}).ValidateState() {
+		err = fmt.Errorf("invalid state %v", state)
+		return
+	}
+
+	packed = PackedSquare{size: uint8(size)}
+	for position, value := range state {
+		cell := position - 1
+		packed.cells |= uint64(value-1) << (PACKED_CELL_BITS * cell)
+		if value == BlankTile(size) {
+			packed.blank = uint8(cell)
+		}
+	}
+	return
+}
+
+// convert any square that fits into a packed square
+func Pack(square MysticSquare) (packed PackedSquare, err error) {
+	if alreadyPacked, ok := square.(PackedSquare); ok {
+		packed = alreadyPacked
+		return
+	}
+	if square == nil {
+		err = fmt.Errorf("missing square")
+		return
+	}
+	packed, err = NewPackedSquare(square.RealState())
+	return
+}
+
+// the packed form of square when it fits, otherwise square itself
+func Compact(square MysticSquare) (compact MysticSquare) {
+	compact = square
+	if square == nil || square.Size() > MAX_PACKED_SIZE {
+		return
+	}
+	if packed, err := Pack(square); err == nil {
+		compact = packed
+	}
+	return
+}
+
+// key identifying the arrangement of the tiles of square
+func KeyOf(square MysticSquare) (key StateKey) {
+	if square.Size() <= MAX_PACKED_SIZE {
+		if packed, err := Pack(square); err == nil {
+			key = packed.Key()
+			return
+		}
+	}
+	key = StateKey{wide: square.State()}
+	return
+}
+
+// key identifying the arrangement of the tiles
+func (packed PackedSquare) Key() (key StateKey) {
+	key = StateKey{packed: packed.cells}
+	return
+}
+
+// value of the tile at the given position, 1..N*N. The blank is N*N.
+func (packed PackedSquare) Tile(position int) (value int) {
+	value = int(packed.cells>>(PACKED_CELL_BITS*(position-1))&packedCellMask) + 1
+	return
+}
+
+// move the empty space in the given direction. moved is false when the move
+// would leave the board.
+func (packed PackedSquare) Step(direction string) (next PackedSquare, moved bool) {
+	size := int(packed.size)
+	blank := int(packed.blank)
+	row, column := blank/size, blank%size
+	target := blank
+	switch {
+	case direction == UP && row > 0:
+		target = blank - size
+	case direction == DOWN && row < size-1:
+		target = blank + size
+	case direction == LEFT && column > 0:
+		target = blank - 1
+	case direction == RIGHT && column < size-1:
+		target = blank + 1
+	default:
+		return
+	}
+
+	shift := PACKED_CELL_BITS * target
+	tile := packed.cells >> shift & packedCellMask
+	blankValue := uint64(BlankTile(size) - 1)
+	next = packed
+	next.cells &^= packedCellMask<<shift | packedCellMask<<(PACKED_CELL_BITS*blank)
+	next.cells |= blankValue<<shift | tile<<(PACKED_CELL_BITS*blank)
+	next.blank = uint8(target)
+	moved = true
+	return
+}
+
+// state map for the result of a step, nil when the move leaves the board
+func (packed PackedSquare) stepState(direction string) (newSquare map[int]int) {
+	if next, moved := packed.Step(direction); moved {
+		newSquare = next.RealState()
+	}
+	return
+}
+
+// return the state string
+func (packed PackedSquare) State() (state string) {
+	state = buildMysticSquareStateString(packed.RealState(), int(packed.size))
+	return
+}
+
+// move the empty space up
+func (packed PackedSquare) MoveUp() (newSquare map[int]int) {
+	newSquare = packed.stepState(UP)
+	return
+}
+
+// move the empty space down
+func (packed PackedSquare) MoveDown() (newSquare map[int]int) {
+	newSquare = packed.stepState(DOWN)
+	return
+}
+
+// move the empty space left
+func (packed PackedSquare) MoveLeft() (newSquare map[int]int) {
+	newSquare = packed.stepState(LEFT)
+	return
+}
+
+// move the empty space right
+func (packed PackedSquare) MoveRight() (newSquare map[int]int) {
+	newSquare = packed.stepState(RIGHT)
+	return
+}
+
+// ensure the packed square is valid. Every tile must appear once and the
+// blank must be where it is recorded.
+func (packed PackedSquare) ValidateState() (validState bool) {
+	size := int(packed.size)
+	if size < MIN_SIZE || size > MAX_PACKED_SIZE {
+		validState = false
+		return
+	}
+	seen := uint32(0)
+	for position := 1; position <= size*size; position++ {
+		value := packed.Tile(position)
+		if value > size*size || seen&(1<<value) != 0 {
+			validState = false
+			return
+		}
+		seen |= 1 << value
+	}
+	validState = packed.Tile(int(packed.blank)+1) == BlankTile(size)
+	return
+}
+
+// find the empty space
+func (packed PackedSquare) FindEmptySpace() (emptySpace int) {
+	emptySpace = int(packed.blank) + 1
+	return
+}
+
+// map for each square to where it would be if moved up, down, left or right
+func (packed PackedSquare) MapKeyToNewKey() (mapping map[int]map[string]int) {
+	mapping = (MysticSquareN{size: int(packed.size)}).MapKeyToNewKey()
+	return
+}
+
+// copy the state to a new map
+func (packed PackedSquare) RealState() (copy map[int]int) {
+	copy = make(map[int]int)
+	for position := 1; position <= int(packed.size)*int(packed.size); position++ {
+		copy[position] = packed.Tile(position)
+	}
+	return
+}
+
+// width of the board
+func (packed PackedSquare) Size() (size int) {
+	size = int(packed.size)
+	return
+}
@@ -0,0 +1,129 @@
+package square
+
+import (
+	"maps"
+	"math/rand/v2"
+	"testing"
+)
+
+// board widths the packed tests cover, with the number of random squares
+// checked on each
+var packedCases = []struct {
+	size    int
+	squares int
+}{
+	{size: 2, squares: 24},
+	{size: 3, squares: 2000},
+	{size: 4, squares: 2000},
+}
+
+// random state map of the given width, blank included
+func randomState(rng *rand.Rand, size int) (state map[int]int) {
+	state = make(map[int]int)
+	for position, value := range rng.Perm(size * size) {
+		state[position+1] = value + 1
+	}
+	return
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	for _, tc := range packedCases {
+		rng := rand.New(rand.NewPCG(uint64(tc.size), 2))
+		for i := 0; i < tc.squares; i++ {
+			state := randomState(rng, tc.size)
+			generic, err := NewMysticSquare(state)
+			if err != nil {
+				t.Fatalf("%vx%v: %v", tc.size, tc.size, err)
+			}
+			packed, err := Pack(generic)
+			if err != nil {
+				t.Fatalf("%vx%v: Pack: %v", tc.size, tc.size, err)
+			}
+			if !packed.ValidateState() {
+				t.Fatalf("%vx%v: packed %v is not valid", tc.size, tc.size, state)
+			}
+			if packed.Size() != tc.size {
+				t.Fatalf("%vx%v: packed square has size %v", tc.size, tc.size, packed.Size())
+			}
+			if !maps.Equal(packed.RealState(), state) {
+				t.Fatalf("%vx%v: unpacked %v, expected %v", tc.size, tc.size, packed.RealState(), state)
+			}
+			if packed.State() != generic.State() {
+				t.Fatalf("%vx%v: state string %q, expected %q", tc.size, tc.size, packed.State(), generic.State())
+			}
+			if packed.FindEmptySpace() != generic.FindEmptySpace() {
+				t.Fatalf("%vx%v: blank at %v, expected %v", tc.size, tc.size, packed.FindEmptySpace(), generic.FindEmptySpace())
+			}
+
+			repacked, err := NewPackedSquare(packed.RealState())
+			if err != nil {
+				t.Fatalf("%vx%v: NewPackedSquare: %v", tc.size, tc.size, err)
+			}
+			if repacked != packed {
+				t.Fatalf("%vx%v: packing %v twice gave different squares", tc.size, tc.size, state)
+			}
+			if again, _ := Pack(packed); again != packed {
+				t.Fatalf("%vx%v: packing a packed square changed it", tc.size, tc.size)
+			}
+			if KeyOf(generic) != packed.Key() {
+				t.Fatalf("%vx%v: key of %v differs from its packed key", tc.size, tc.size, state)
+			}
+		}
+	}
+}
+
+// every step of a packed square must match the same move on a state map
+func TestPackedStep(t *testing.T) {
+	for _, tc := range packedCases {
+		rng := rand.New(rand.NewPCG(uint64(tc.size), 3))
+		for i := 0; i < tc.squares; i++ {
+			generic, err := NewMysticSquare(randomState(rng, tc.size))
+			if err != nil {
+				t.Fatalf("%vx%v: %v", tc.size, tc.size, err)
+			}
+			packed, _ := Pack(generic)
+			moves := map[string]func(MysticSquare) map[int]int{
+				UP:    MysticSquare.MoveUp,
+				DOWN:  MysticSquare.MoveDown,
+				LEFT:  MysticSquare.MoveLeft,
+				RIGHT: MysticSquare.MoveRight,
+			}
+			for direction, move := range moves {
+				expected := move(generic)
+				next, moved := packed.Step(direction)
+				if moved != (expected != nil) {
+					t.Fatalf("%vx%v: step %v of %v moved %v", tc.size, tc.size, direction, generic.RealState(), moved)
+				}
+				if !moved {
+					continue
+				}
+				if !next.ValidateState() {
+					t.Fatalf("%vx%v: step %v of %v is not valid", tc.size, tc.size, direction, generic.RealState())
+				}
+				if !maps.Equal(next.RealState(), expected) {
+					t.Fatalf("%vx%v: step %v gave %v, expected %v", tc.size, tc.size, direction, next.RealState(), expected)
+				}
+				if !maps.Equal(move(packed), expected) {
+					t.Fatalf("%vx%v: packed move %v gave %v, expected %v", tc.size, tc.size, direction, move(packed), expected)
+				}
+			}
+		}
+	}
+}
+
+func TestNewPackedSquareRejects(t *testing.T) {
+	cases := []struct {
+		name  string
+		state map[int]int
+	}{
+		{name: "not square", state: map[int]int{1: 1, 2: 2, 3: 3}},
+		{name: "repeated tile", state: map[int]int{1: 1, 2: 1, 3: 3, 4: 4}},
+		{name: "missing blank", state: map[int]int{1: 1, 2: 2, 3: 3, 4: 5}},
+		{name: "too wide", state: SolvedState(MAX_PACKED_SIZE + 1)},
+	}
+	for _, tc := range cases {
+		if _, err := NewPackedSquare(tc.state); err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		}
+	}
+}
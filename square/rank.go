@@ -0,0 +1,208 @@
+package square
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// widest board whose arrangements can be ranked into a uint64. 16! still
+// fits, 25! does not.
+const MAX_RANK_SIZE = MAX_PACKED_SIZE
+
+// n!
+func factorial(n int) (product uint64) {
+	product = 1
+	for i := 2; i <= n; i++ {
+		product *= uint64(i)
+	}
+	return
+}
+
+// number of arrangements of the tiles and the blank on the board, (N*N)!
+func PermutationCount(size int) (count uint64) {
+	count = factorial(size * size)
+	return
+}
+
+// number of arrangements reachable from any single board, (N*N)!/2
+func ReachableCount(size int) (count uint64) {
+	count = PermutationCount(size) / 2
+	return
+}
+
+// parity that no sequence of moves can change. Two squares of the same width
+// can reach each other exactly when their parities are equal.
+func Parity(square MysticSquare) (parity int) {
+	parity = invariantParity(square)
+	return
+}
+
+// Lehmer code of a permutation of 0..len(values)-1 read as a mixed radix
+// number. Digit i counts the later values smaller than values[i] and has
+// radix len(values)-i, so the rank is dense over all len(values)! orders.
+func lehmerRank(values []int) (rank uint64) {
+	used := uint32(0)
+	for i, value := range values {
+		digit := value - bits.OnesCount32(used&(1<<value-1))
+		rank = rank*uint64(len(values)-i) + uint64(digit)
+		used |= 1 << value
+	}
+	return
+}
+
+// inverse of lehmerRank. Fills values with the permutation of the given rank
+// and returns the parity of its inversion count.
+func lehmerUnrank(rank uint64, values []int) (parity int) {
+	m := len(values)
+	for i := m - 1; i >= 0; i-- {
+		values[i] = int(rank % uint64(m-i))
+		rank /= uint64(m - i)
+		parity += values[i]
+	}
+	parity %= 2
+
+	used := uint32(0)
+	for i, digit := range values {
+		for value := 0; ; value++ {
+			if used&(1<<value) != 0 {
+				continue
+			}
+			if digit == 0 {
+				values[i] = value
+				used |= 1 << value
+				break
+			}
+			digit--
+		}
+	}
+	return
+}
+
+// check a square can be ranked and return its width
+func rankableSize(square MysticSquare) (size int, err error) {
+	if square == nil || !square.ValidateState() {
+		err = fmt.Errorf("invalid square")
+		return
+	}
+	if size = square.Size(); size > MAX_RANK_SIZE {
+		err = fmt.Errorf("a %vx%v board has too many arrangements to rank", size, size)
+		size = 0
+	}
+	return
+}
+
+// value of every cell minus one, row by row, so the blank is N*N-1. Packed
+// squares are read without building a state map.
+func cellValues(square MysticSquare, size int) (values []int) {
+	values = make([]int, size*size)
+	if packed, ok := square.(PackedSquare); ok {
+		for position := range values {
+			values[position] = packed.Tile(position+1) - 1
+		}
+		return
+	}
+	state := square.RealState()
+	for position := range values {
+		values[position] = state[position+1] - 1
+	}
+	return
+}
+
+// packed square holding the given cell values, each the tile value minus one.
+// The values must be a permutation of 0..size*size-1.
+func packCells(size int, values []int) (packed PackedSquare) {
+	packed = PackedSquare{size: uint8(size)}
+	for cell, value := range values {
+		packed.cells |= uint64(value) << (PACKED_CELL_BITS * cell)
+		if value == BlankTile(size)-1 {
+			packed.blank = uint8(cell)
+		}
+	}
+	return
+}
+
+// dense index of the square among all (N*N)! arrangements of its board
+func Rank(square MysticSquare) (rank uint64, err error) {
+	size, sizeErr := rankableSize(square)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	rank = lehmerRank(cellValues(square, size))
+	return
+}
+
+// square of the given rank among all (N*N)! arrangements. Inverse of Rank.
+func Unrank(size int, rank uint64) (square PackedSquare, err error) {
+	if size < MIN_SIZE || size > MAX_RANK_SIZE {
+		err = fmt.Errorf("can not unrank a %vx%v board", size, size)
+		return
+	}
+	if rank >= PermutationCount(size) {
+		err = fmt.Errorf("rank %v is out of range for a %vx%v board", rank, size, size)
+		return
+	}
+	values := make([]int, size*size)
+	lehmerUnrank(rank, values)
+	square = packCells(size, values)
+	return
+}
+
+// dense index of the square among the (N*N)!/2 arrangements that share its
+// parity, which are exactly the squares it can reach.
+//
+// The rank combines the cell of the blank with the order of the tiles. Once
+// the blank is placed the parity fixes the parity of the tile order, and
+// within one parity the last Lehmer digit of the tiles is implied by the
+// others, so halving their rank keeps it dense.
+func ReachableRank(square MysticSquare) (rank uint64, err error) {
+	size, sizeErr := rankableSize(square)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	blank := BlankTile(size)
+	tiles := make([]int, 0, blank-1)
+	for _, value := range cellValues(square, size) {
+		if value != blank-1 {
+			tiles = append(tiles, value)
+		}
+	}
+	rank = uint64(square.FindEmptySpace()-1)*(factorial(blank-1)/2) + lehmerRank(tiles)/2
+	return
+}
+
+// square of the given rank among those that can reach reference. Inverse of
+// ReachableRank for squares with the parity of reference.
+func ReachableUnrank(reference MysticSquare, rank uint64) (square PackedSquare, err error) {
+	size, sizeErr := rankableSize(reference)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	if rank >= ReachableCount(size) {
+		err = fmt.Errorf("rank %v is out of range for a %vx%v board", rank, size, size)
+		return
+	}
+	blank := BlankTile(size)
+	orders := factorial(blank-1) / 2
+	blankCell := int(rank / orders)
+
+	// parity the tile order must have for the square to share the parity of
+	// reference, see invariantParity
+	tileParity := Parity(reference)
+	if size%2 == 0 {
+		tileParity = (tileParity + blankCell/size) % 2
+	}
+	tiles := make([]int, blank-1)
+	if lehmerUnrank(rank%orders*2, tiles) != tileParity {
+		lehmerUnrank(rank%orders*2+1, tiles)
+	}
+
+	values := make([]int, 0, blank)
+	values = append(values, tiles[:blankCell]...)
+	values = append(values, blank-1)
+	values = append(values, tiles[blankCell:]...)
+	square = packCells(size, values)
+	return
+}
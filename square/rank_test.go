@@ -0,0 +1,202 @@
+package square
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// board widths the rank tests cover. Boards with few enough arrangements are
+// checked rank by rank, the others on a sample of ranks.
+var rankCases = []struct {
+	size       int
+	exhaustive bool
+}{
+	{size: 2, exhaustive: true},
+	{size: 3, exhaustive: true},
+	{size: 4, exhaustive: false},
+}
+
+// number of ranks sampled for boards that are not checked exhaustively
+const SAMPLED_RANKS = 20000
+
+// every rank to check for a board, or a fixed sample of them
+func ranksToCheck(size int, exhaustive bool, count uint64) (ranks []uint64) {
+	if exhaustive {
+		ranks = make([]uint64, count)
+		for rank := range ranks {
+			ranks[rank] = uint64(rank)
+		}
+		return
+	}
+	rng := rand.New(rand.NewPCG(uint64(size), 1))
+	ranks = []uint64{0, count - 1}
+	for len(ranks) < SAMPLED_RANKS {
+		ranks = append(ranks, rng.Uint64N(count))
+	}
+	return
+}
+
+// a square of the given width and parity
+func squareWithParity(t *testing.T, size, parity int) (reference MysticSquare) {
+	t.Helper()
+	state := SolvedState(size)
+	if parity != Parity(MysticSquareN{state: state, size: size}) {
+		state[1], state[2] = state[2], state[1]
+	}
+	reference, err := NewMysticSquare(state)
+	if err != nil {
+		t.Fatalf("reference: %v", err)
+	}
+	if Parity(reference) != parity {
+		t.Fatalf("reference has parity %v, expected %v", Parity(reference), parity)
+	}
+	return
+}
+
+func TestRankRoundTrip(t *testing.T) {
+	for _, tc := range rankCases {
+		count := PermutationCount(tc.size)
+		for _, rank := range ranksToCheck(tc.size, tc.exhaustive, count) {
+			unranked, err := Unrank(tc.size, rank)
+			if err != nil {
+				t.Fatalf("%vx%v: Unrank(%v): %v", tc.size, tc.size, rank, err)
+			}
+			if !unranked.ValidateState() {
+				t.Fatalf("%vx%v: Unrank(%v) is not a valid square", tc.size, tc.size, rank)
+			}
+			got, err := Rank(unranked)
+			if err != nil {
+				t.Fatalf("%vx%v: Rank(Unrank(%v)): %v", tc.size, tc.size, rank, err)
+			}
+			if got != rank {
+				t.Fatalf("%vx%v: Rank(Unrank(%v)) = %v", tc.size, tc.size, rank, got)
+			}
+
+			// the same arrangement held in a state map ranks the same
+			generic, err := NewMysticSquare(unranked.RealState())
+			if err != nil {
+				t.Fatalf("%vx%v: %v", tc.size, tc.size, err)
+			}
+			if got, _ := Rank(generic); got != rank {
+				t.Fatalf("%vx%v: Rank of the unpacked square %v = %v", tc.size, tc.size, rank, got)
+			}
+		}
+	}
+}
+
+func TestRankDensity(t *testing.T) {
+	for _, tc := range rankCases {
+		if !tc.exhaustive {
+			continue
+		}
+		count := PermutationCount(tc.size)
+		seen := make(map[StateKey]bool, count)
+		for rank := uint64(0); rank < count; rank++ {
+			unranked, err := Unrank(tc.size, rank)
+			if err != nil {
+				t.Fatalf("%vx%v: Unrank(%v): %v", tc.size, tc.size, rank, err)
+			}
+			if seen[unranked.Key()] {
+				t.Fatalf("%vx%v: rank %v repeats an earlier square", tc.size, tc.size, rank)
+			}
+			seen[unranked.Key()] = true
+		}
+		if _, err := Unrank(tc.size, count); err == nil {
+			t.Errorf("%vx%v: Unrank(%v) should be out of range", tc.size, tc.size, count)
+		}
+	}
+}
+
+func TestReachableRankRoundTrip(t *testing.T) {
+	for _, tc := range rankCases {
+		count := ReachableCount(tc.size)
+		for parity := 0; parity < 2; parity++ {
+			reference := squareWithParity(t, tc.size, parity)
+			for _, rank := range ranksToCheck(tc.size, tc.exhaustive, count) {
+				unranked, err := ReachableUnrank(reference, rank)
+				if err != nil {
+					t.Fatalf("%vx%v parity %v: ReachableUnrank(%v): %v", tc.size, tc.size, parity, rank, err)
+				}
+				if !unranked.ValidateState() {
+					t.Fatalf("%vx%v parity %v: ReachableUnrank(%v) is not a valid square", tc.size, tc.size, parity, rank)
+				}
+				if Parity(unranked) != parity {
+					t.Fatalf("%vx%v parity %v: ReachableUnrank(%v) has parity %v", tc.size, tc.size, parity, rank, Parity(unranked))
+				}
+				got, err := ReachableRank(unranked)
+				if err != nil {
+					t.Fatalf("%vx%v parity %v: ReachableRank: %v", tc.size, tc.size, parity, err)
+				}
+				if got != rank {
+					t.Fatalf("%vx%v parity %v: ReachableRank(ReachableUnrank(%v)) = %v", tc.size, tc.size, parity, rank, got)
+				}
+			}
+			if _, err := ReachableUnrank(reference, count); err == nil {
+				t.Errorf("%vx%v: ReachableUnrank(%v) should be out of range", tc.size, tc.size, count)
+			}
+		}
+	}
+}
+
+// every arrangement of one parity must take a distinct rank in
+// [0, (N*N)!/2), so the ranks of a parity cover that range exactly once
+func TestReachableRankDensity(t *testing.T) {
+	for _, tc := range rankCases {
+		if !tc.exhaustive {
+			continue
+		}
+		count := ReachableCount(tc.size)
+		seen := [2][]bool{make([]bool, count), make([]bool, count)}
+		for rank := uint64(0); rank < PermutationCount(tc.size); rank++ {
+			current, _ := Unrank(tc.size, rank)
+			reachable, err := ReachableRank(current)
+			if err != nil {
+				t.Fatalf("%vx%v: ReachableRank: %v", tc.size, tc.size, err)
+			}
+			parity := Parity(current)
+			if reachable >= count {
+				t.Fatalf("%vx%v: ReachableRank = %v, not below %v", tc.size, tc.size, reachable, count)
+			}
+			if seen[parity][reachable] {
+				t.Fatalf("%vx%v parity %v: two squares share rank %v", tc.size, tc.size, parity, reachable)
+			}
+			seen[parity][reachable] = true
+		}
+		for parity := range seen {
+			for rank, used := range seen[parity] {
+				if !used {
+					t.Fatalf("%vx%v parity %v: no square has rank %v", tc.size, tc.size, parity, rank)
+				}
+			}
+		}
+	}
+}
+
+// parity of the pairs of tiles out of order, counted pair by pair
+func pairwiseInversionParity(square MysticSquare) (parity int) {
+	size := square.Size()
+	state := square.RealState()
+	for i := 1; i <= size*size; i++ {
+		for j := i + 1; j <= size*size; j++ {
+			if state[i] != BlankTile(size) && state[j] != BlankTile(size) && state[i] > state[j] {
+				parity ^= 1
+			}
+		}
+	}
+	return
+}
+
+func TestInversionParity(t *testing.T) {
+	for _, tc := range rankCases {
+		count := PermutationCount(tc.size)
+		for _, rank := range ranksToCheck(tc.size, false, count)[:1000] {
+			unranked, err := Unrank(tc.size, rank)
+			if err != nil {
+				t.Fatalf("%vx%v: Unrank(%v): %v", tc.size, tc.size, rank, err)
+			}
+			if got, expected := inversionParity(unranked), pairwiseInversionParity(unranked); got != expected {
+				t.Fatalf("%vx%v: inversion parity of %v is %v, expected %v", tc.size, tc.size, FormatCompact(unranked), got, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package square
+
+import (
+	"fmt"
+)
+
+// distance recorded by ReachableDistances for a rank no square has, which
+// only happens when the table is read with a reference of another parity
+const UNREACHED_DISTANCE = 255
+
+// most reachable arrangements a sweep should visit. The sweep keeps one byte
+// per arrangement, so this bounds it to a few GiB. Every 3x3 board fits, no
+// 4x4 board does.
+const MAX_SWEEP_STATES = 1 << 32
+
+// number of moves from every square that can reach target to target, indexed
+// by ReachableRank. Found with a breadth first sweep outward from target, so
+// it refuses boards with more than maxStates reachable arrangements.
+func ReachableDistances(target MysticSquare, maxStates uint64) (distances []uint8, err error) {
+	size, sizeErr := rankableSize(target)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	if count := ReachableCount(size); count > maxStates {
+		err = fmt.Errorf("a %vx%v board has %v reachable arrangements, more than the limit of %v", size, size, count, maxStates)
+		return
+	}
+	start, packErr := Pack(target)
+	if packErr != nil {
+		err = packErr
+		return
+	}
+
+	distances = make([]uint8, ReachableCount(size))
+	for idx := range distances {
+		distances[idx] = UNREACHED_DISTANCE
+	}
+	startRank, _ := ReachableRank(start)
+	distances[startRank] = 0
+
+	frontier := []PackedSquare{start}
+	for distance := 1; len(frontier) > 0; distance++ {
+		if distance >= UNREACHED_DISTANCE {
+			distances = nil
+			err = fmt.Errorf("a %vx%v board is more than %v moves across", size, size, UNREACHED_DISTANCE-1)
+			return
+		}
+		next := make([]PackedSquare, 0, len(frontier)*2)
+		for _, current := range frontier {
+			for _, direction := range []string{LEFT, RIGHT, UP, DOWN} {
+				neighbor, moved := current.Step(direction)
+				if !moved {
+					continue
+				}
+				rank, _ := ReachableRank(neighbor)
+				if distances[rank] == UNREACHED_DISTANCE {
+					distances[rank] = uint8(distance)
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+	return
+}
@@ -2,7 +2,9 @@ package square
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 type MysticSquare interface {
@@ -15,57 +17,92 @@ type MysticSquare interface {
 	FindEmptySpace() int
 	MapKeyToNewKey() map[int]map[string]int
 	RealState() map[int]int
+	Size() int
 }
 
-type MysticSquare3 struct {
+// smallest board width that can be built
+const MIN_SIZE = 2
+
+// directions the empty space can move in
+const (
+	UP    = "up"
+	DOWN  = "down"
+	LEFT  = "left"
+	RIGHT = "right"
+)
+
+// an NxN mystic square. Positions are numbered 1..N*N row by row and the
+// blank tile is represented by the value N*N.
+type MysticSquareN struct {
 	state    map[int]int
+	size     int
 	strState string
 }
 
-// convert the state map to a state string
-func buildMysticSquare3StateString(state map[int]int) (strState string) {
-	buildRow := func(squareState map[int]int, row int) (rowString string) {
-		c1 := strconv.Itoa(squareState[1+3*(row-1)])
-		c2 := strconv.Itoa(squareState[2+3*(row-1)])
-		c3 := strconv.Itoa(squareState[3+3*(row-1)])
+// a 3x3 mystic square
+type MysticSquare3 = MysticSquareN
 
-		if c1 == "9" {
-			c1 = " "
-		}
+// value used to represent the blank tile on a board of the given width
+func BlankTile(size int) (blank int) {
+	blank = size * size
+	return
+}
 
-		if c2 == "9" {
-			c2 = " "
-		}
+// determine the board width from the number of tiles in the state
+func SizeForState(state map[int]int) (size int, err error) {
+	size = int(math.Round(math.Sqrt(float64(len(state)))))
+	if size < MIN_SIZE || size*size != len(state) {
+		size = 0
+		err = fmt.Errorf("state with %v tiles is not a square board", len(state))
+	}
+	return
+}
 
-		if c3 == "9" {
-			c3 = " "
+// convert the state map to a state string
+func buildMysticSquareStateString(state map[int]int, size int) (strState string) {
+	blank := BlankTile(size)
+	width := len(strconv.Itoa(blank - 1))
+	buildRow := func(squareState map[int]int, row int) (rowString string) {
+		cells := make([]string, size)
+		for column := 1; column <= size; column++ {
+			value := squareState[column+size*(row-1)]
+			cell := strconv.Itoa(value)
+			if value == blank {
+				cell = " "
+			}
+			cells[column-1] = fmt.Sprintf("%*s", width, cell)
 		}
 
-		rowString = fmt.Sprintf("%s %s %s", c1, c2, c3)
+		rowString = strings.Join(cells, " ")
 		return
 	}
 
-	if validState := (MysticSquare3{state: state}).ValidateState(); validState {
-		squareState := state
-		row1 := buildRow(squareState, 1)
-		row2 := buildRow(squareState, 2)
-		row3 := buildRow(squareState, 3)
-		strState = fmt.Sprintf("%s\n%s\n%s", row1, row2, row3)
+	if validState := (MysticSquareN{state: state, size: size}).ValidateState(); validState {
+		rows := make([]string, size)
+		for row := 1; row <= size; row++ {
+			rows[row-1] = buildRow(state, row)
+		}
+		strState = strings.Join(rows, "\n")
 	} else {
 		strState = "Invalid State"
 	}
 	return
 }
 
-// create a new mystic square
+// create a new mystic square. The size of the board is chosen from the state.
 func NewMysticSquare(state map[int]int) (newSquare MysticSquare, err error) {
-	newSquare, err = NewMysticSquare3(state)
+	size, sizeErr := SizeForState(state)
+	if sizeErr != nil {
+		err = sizeErr
+		return
+	}
+	newSquare, err = NewMysticSquareN(state, size)
 	return
 }
 
-// create a new 3x3 mystic square
-func NewMysticSquare3(state map[int]int) (newSquare *MysticSquare3, err error) {
-	newSquare = &MysticSquare3{state: state, strState: buildMysticSquare3StateString(state)}
+// create a new NxN mystic square
+func NewMysticSquareN(state map[int]int, size int) (newSquare *MysticSquareN, err error) {
+	newSquare = &MysticSquareN{state: state, size: size, strState: buildMysticSquareStateString(state, size)}
 	err = nil
 	if validState := newSquare.ValidateState(); !validState {
 		newSquare = nil
@@ -74,13 +111,19 @@ func NewMysticSquare3(state map[int]int) (newSquare *MysticSquare3, err error) {
 	return
 }
 
-// move the empty space up
-func (square MysticSquare3) MoveUp() (newSquare map[int]int) {
+// create a new 3x3 mystic square
+func NewMysticSquare3(state map[int]int) (newSquare *MysticSquare3, err error) {
+	newSquare, err = NewMysticSquareN(state, 3)
+	return
+}
+
+// swap the blank with its neighbor in the given direction
+func (square MysticSquareN) move(direction string) (newSquare map[int]int) {
 	if validState := square.ValidateState(); validState {
 		blankSpace := square.FindEmptySpace()
 		boardMapping := square.MapKeyToNewKey()
 		if spaceMapping, spaceMappingExists := boardMapping[blankSpace]; spaceMappingExists {
-			if newSpace, upExists := spaceMapping["up"]; upExists {
+			if newSpace, directionExists := spaceMapping[direction]; directionExists {
 				newSquare = make(map[int]int)
 				oldSquare := square.state
 				for key, val := range oldSquare {
@@ -96,102 +139,75 @@ func (square MysticSquare3) MoveUp() (newSquare map[int]int) {
 	return
 }
 
-// move the empty space down
-func (square MysticSquare3) MoveDown() (newSquare map[int]int) {
-	if validState := square.ValidateState(); validState {
-		blankSpace := square.FindEmptySpace()
-		boardMapping := square.MapKeyToNewKey()
-		if spaceMapping, spaceMappingExists := boardMapping[blankSpace]; spaceMappingExists {
-			if newSpace, downExists := spaceMapping["down"]; downExists {
-				newSquare = make(map[int]int)
-				oldSquare := square.state
-				for key, val := range oldSquare {
-					newSquare[key] = val
-				}
+// move the empty space up
+func (square MysticSquareN) MoveUp() (newSquare map[int]int) {
+	newSquare = square.move(UP)
+	return
+}
 
-				temp := newSquare[blankSpace]
-				newSquare[blankSpace] = newSquare[newSpace]
-				newSquare[newSpace] = temp
-			}
-		}
-	}
+// move the empty space down
+func (square MysticSquareN) MoveDown() (newSquare map[int]int) {
+	newSquare = square.move(DOWN)
 	return
 }
 
 // move the empty space left
-func (square MysticSquare3) MoveLeft() (newSquare map[int]int) {
-	if validState := square.ValidateState(); validState {
-		blankSpace := square.FindEmptySpace()
-		boardMapping := square.MapKeyToNewKey()
-		if spaceMapping, spaceMappingExists := boardMapping[blankSpace]; spaceMappingExists {
-			if newSpace, leftExists := spaceMapping["left"]; leftExists {
-				newSquare = make(map[int]int)
-				oldSquare := square.state
-				for key, val := range oldSquare {
-					newSquare[key] = val
-				}
-
-				temp := newSquare[blankSpace]
-				newSquare[blankSpace] = newSquare[newSpace]
-				newSquare[newSpace] = temp
-			}
-		}
-	}
+func (square MysticSquareN) MoveLeft() (newSquare map[int]int) {
+	newSquare = square.move(LEFT)
 	return
 }
 
 // move the empty space right
-func (square MysticSquare3) MoveRight() (newSquare map[int]int) {
-	if validState := square.ValidateState(); validState {
-		blankSpace := square.FindEmptySpace()
-		boardMapping := square.MapKeyToNewKey()
-		if spaceMapping, spaceMappingExists := boardMapping[blankSpace]; spaceMappingExists {
-			if newSpace, rightExists := spaceMapping["right"]; rightExists {
-				newSquare = make(map[int]int)
-				oldSquare := square.state
-				for key, val := range oldSquare {
-					newSquare[key] = val
-				}
-
-				temp := newSquare[blankSpace]
-				newSquare[blankSpace] = newSquare[newSpace]
-				newSquare[newSpace] = temp
-			}
-		}
-	}
+func (square MysticSquareN) MoveRight() (newSquare map[int]int) {
+	newSquare = square.move(RIGHT)
 	return
 }
 
 // map for each square to where it would be if moved up, down, left or right
-func (square MysticSquare3) MapKeyToNewKey() (mapping map[int]map[string]int) {
-	mapping = map[int]map[string]int{
-		1: {"down": 4, "right": 2},
-		2: {"down": 5, "left": 1, "right": 3},
-		3: {"down": 6, "left": 2},
-		4: {"up": 1, "down": 7, "right": 5},
-		5: {"up": 2, "down": 8, "left": 4, "right": 6},
-		6: {"up": 3, "down": 9, "left": 5},
-		7: {"up": 4, "right": 8},
-		8: {"up": 5, "left": 7, "right": 9},
-		9: {"up": 6, "left": 8},
+func (square MysticSquareN) MapKeyToNewKey() (mapping map[int]map[string]int) {
+	size := square.size
+	mapping = make(map[int]map[string]int)
+	for position := 1; position <= size*size; position++ {
+		row, column := (position-1)/size, (position-1)%size
+		moves := make(map[string]int)
+		if row > 0 {
+			moves[UP] = position - size
+		}
+		if row < size-1 {
+			moves[DOWN] = position + size
+		}
+		if column > 0 {
+			moves[LEFT] = position - 1
+		}
+		if column < size-1 {
+			moves[RIGHT] = position + 1
+		}
+		mapping[position] = moves
 	}
 
 	return
 }
 
 // return the state string
-func (square MysticSquare3) State() (state string) {
+func (square MysticSquareN) State() (state string) {
 
 	state = square.strState
 	return
 }
 
+// width of the board
+func (square MysticSquareN) Size() (size int) {
+	size = square.size
+	return
+}
+
 // find the empty space
-func (square MysticSquare3) FindEmptySpace() (emptySpace int) {
+func (square MysticSquareN) FindEmptySpace() (emptySpace int) {
 	emptySpace = -1
+	blank := BlankTile(square.size)
 	state := square.state
 	for key, val := range state {
-		if val == 9 {
+		if val == blank {
 			emptySpace = key
 			break
 		}
@@ -200,53 +216,127 @@ func (square MysticSquare3) FindEmptySpace() (emptySpace int) {
 }
 
 // ensure the mystic square is valid
-func (square MysticSquare3) ValidateState() (validState bool) {
-	validTilePositions := make([]int, 9)
-	validTileValues := make([]int, 9)
+func (square MysticSquareN) ValidateState() (validState bool) {
+	tiles := square.size * square.size
 	board := square.state
-	if len(board) != len(validTilePositions) {
+	if square.size < MIN_SIZE || len(board) != tiles {
 		validState = false
 		return
 	}
-	for i := 1; i < 10; i++ {
-		validTilePositions[i-1] = i
-		validTileValues[i-1] = i
+	seenValues := make([]bool, tiles+1)
+	for key, value := range board {
+		if key < 1 || key > tiles || value < 1 || value > tiles || seenValues[value] {
+			validState = false
+			return
+		}
+		seenValues[value] = true
 	}
-	find := func(A []int, value int) (index int) {
-		for idx, val := range A {
-			if value == val {
-				index = idx
-				return
-			}
+
+	validState = true
+	return
+}
+
+// copy the state from the square to a new map.
+func (square MysticSquareN) RealState() (copy map[int]int) {
+	copy = make(map[int]int)
+	for key, value := range square.state {
+		copy[key] = value
+
+	}
+	return
+}
+
+// parity of the number of pairs of tiles, ignoring the blank, that are out of
+// order when the board is read row by row. Tiles forming c cycles take
+// tiles-c exchanges to sort and each exchange flips the parity, so counting
+// cycles takes linear time instead of comparing every pair.
+func inversionParity(square MysticSquare) (parity int) {
+	size := square.Size()
+	blank := BlankTile(size)
+	state := square.RealState()
+	tiles := make([]int, 0, size*size)
+	for position := 1; position <= size*size; position++ {
+		if value := state[position]; value != blank {
+			tiles = append(tiles, value)
 		}
-		return -1
 	}
-	remove := func(A []int, value int) (arr []int) {
-		if idx := find(A, value); idx >= 0 && idx < len(A) {
-			arr = append(A[:idx], A[idx+1:]...)
-		} else {
-			arr = A
+	visited := make([]bool, len(tiles))
+	cycles := 0
+	for start := range tiles {
+		if visited[start] {
+			continue
 		}
+		cycles++
+		for index := start; !visited[index]; index = tiles[index] - 1 {
+			visited[index] = true
+		}
+	}
+	parity = (len(tiles) - cycles) % 2
+	return
+}
 
+// parity that no sequence of moves can change. On odd width boards it is the
+// inversion parity. On even width boards a vertical move also changes the
+// inversion count by an odd amount, so the row of the blank is included.
+func invariantParity(square MysticSquare) (parity int) {
+	parity = inversionParity(square)
+	if size := square.Size(); size%2 == 0 {
+		parity += (square.FindEmptySpace() - 1) / size
+	}
+	parity %= 2
+	return
+}
+
+// check whether the target can be reached from the initial square. When it
+// can not, reason explains why.
+func Solvable(initial, target MysticSquare) (solvable bool, reason string) {
+	if initial == nil || target == nil {
+		solvable = false
+		reason = "missing initial or target square"
 		return
 	}
-	for key, value := range board {
-		validTilePositions = remove(validTilePositions, key)
-		validTileValues = remove(validTileValues, value)
+
+	if !initial.ValidateState() || !target.ValidateState() {
+		solvable = false
+		reason = "initial or target square is invalid"
+		return
+	}
+
+	if initial.Size() != target.Size() {
+		solvable = false
+		reason = fmt.Sprintf("board widths differ: %v, %v", initial.Size(), target.Size())
+		return
+	}
+
+	initialParity, targetParity := invariantParity(initial), invariantParity(target)
+	if initialParity != targetParity {
+		solvable = false
+		if initial.Size()%2 == 0 {
+			reason = fmt.Sprintf("inversion count plus blank row parity differs on a %vx%v board: initial %v, target %v", initial.Size(), initial.Size(), initialParity, targetParity)
+		} else {
+			reason = fmt.Sprintf("inversion count parity differs on a %vx%v board: initial %v, target %v", initial.Size(), initial.Size(), initialParity, targetParity)
+		}
+		return
 	}
 
-	allKeysFound := len(validTilePositions) == 0
-	allValuesFound := len(validTileValues) == 0
-	validState = allKeysFound && allValuesFound
+	solvable = true
+	reason = ""
 	return
 }
 
-// copy the state from the square to a new map.
-func (square MysticSquare3) RealState() (copy map[int]int) {
-	copy = make(map[int]int)
-	for key, value := range square.state {
-		copy[key] = value
-
+// direction the empty space moved to turn from into to, one of UP, DOWN, LEFT
+// or RIGHT. Empty when to is not a single move away from from.
+func MoveBetween(from, to MysticSquare) (direction string) {
+	direction = ""
+	if from == nil || to == nil || from.Size() != to.Size() {
+		return
+	}
+	blank := from.FindEmptySpace()
+	for candidate, position := range from.MapKeyToNewKey()[blank] {
+		if to.FindEmptySpace() == position && to.RealState()[blank] == from.RealState()[position] {
+			direction = candidate
+			break
+		}
 	}
 	return
 }
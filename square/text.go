@@ -0,0 +1,133 @@
+package square
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// separators accepted between tiles and rows in the compact board format
+const COMPACT_SEPARATORS = ",/;"
+
+// separates the start board from the target board of a puzzle. Boards may
+// contain whitespace, so it cannot be used for this.
+const PUZZLE_TARGET_SEPARATOR = "->"
+
+// tokens accepted for the blank tile in the compact board format
+var blankTokens = map[string]bool{"0": true, "_": true, ".": true}
+
+// state for the solved board of the given width: tiles in order with the
+// blank in the last position
+func SolvedState(size int) (state map[int]int) {
+	state = make(map[int]int)
+	for position := 1; position <= size*size; position++ {
+		state[position] = position
+	}
+	return
+}
+
+// parse a board in the compact text form into a state map.
+//
+// Tiles are listed row by row separated by commas, slashes, semicolons or
+// whitespace, e.g. "0,1,2/4,6,3/7,5,8". The blank may be written as 0, _ or .
+// or as N*N. A single token without separators is read one character per
+// tile, e.g. "_12463758", which is only useful for boards up to 3x3.
+func ParseState(text string) (state map[int]int, err error) {
+	isSeparator := func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(COMPACT_SEPARATORS, r)
+	}
+	tokens := strings.FieldsFunc(text, isSeparator)
+	if len(tokens) == 1 {
+		tokens = strings.Split(tokens[0], "")
+	}
+	if len(tokens) == 0 {
+		err = fmt.Errorf("empty board")
+		return
+	}
+
+	size := 0
+	for width := MIN_SIZE; width*width <= len(tokens); width++ {
+		if width*width == len(tokens) {
+			size = width
+		}
+	}
+	if size == 0 {
+		err = fmt.Errorf("%v tiles do not make a square board", len(tokens))
+		return
+	}
+
+	state = make(map[int]int)
+	for idx, token := range tokens {
+		if blankTokens[token] {
+			state[idx+1] = BlankTile(size)
+			continue
+		}
+		value, convErr := strconv.Atoi(token)
+		if convErr != nil {
+			state = nil
+			err = fmt.Errorf("tile %v is not a number: %q", idx+1, token)
+			return
+		}
+		state[idx+1] = value
+	}
+	return
+}
+
+// parse a board in the compact text form and build a mystic square from it
+func ParseMysticSquare(text string) (newSquare MysticSquare, err error) {
+	state, parseErr := ParseState(text)
+	if parseErr != nil {
+		err = fmt.Errorf("could not parse board %q: %v", text, parseErr)
+		return
+	}
+	if newSquare, err = NewMysticSquare(state); err != nil {
+		newSquare = nil
+		err = fmt.Errorf("board %q is not a valid mystic square: every tile 1..N*N-1 and one blank must appear exactly once", text)
+	}
+	return
+}
+
+// parse a puzzle of the form "start" or "start -> target", as read by run
+// --batch, into its start and target squares. Without a target the solved
+// board of the same size is used.
+func ParsePuzzle(text string) (initialSquare, targetSquare MysticSquare, err error) {
+	startText, targetText, hasTarget := strings.Cut(text, PUZZLE_TARGET_SEPARATOR)
+	if hasTarget && strings.Contains(targetText, PUZZLE_TARGET_SEPARATOR) {
+		err = fmt.Errorf("more than one %q in the puzzle", PUZZLE_TARGET_SEPARATOR)
+		return
+	}
+	if initialSquare, err = ParseMysticSquare(strings.TrimSpace(startText)); err != nil {
+		return
+	}
+	if !hasTarget {
+		targetSquare, err = NewMysticSquare(SolvedState(initialSquare.Size()))
+	} else {
+		targetSquare, err = ParseMysticSquare(strings.TrimSpace(targetText))
+	}
+	if err != nil {
+		initialSquare, targetSquare = nil, nil
+	}
+	return
+}
+
+// format a square in the compact text form accepted by ParseMysticSquare
+func FormatCompact(square MysticSquare) (text string) {
+	size := square.Size()
+	blank := BlankTile(size)
+	state := square.RealState()
+	rows := make([]string, size)
+	for row := 0; row < size; row++ {
+		cells := make([]string, size)
+		for column := 0; column < size; column++ {
+			value := state[1+column+size*row]
+			if value == blank {
+				value = 0
+			}
+			cells[column] = strconv.Itoa(value)
+		}
+		rows[row] = strings.Join(cells, ",")
+	}
+	text = strings.Join(rows, "/")
+	return
+}